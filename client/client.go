@@ -6,12 +6,14 @@ import (
 	"net"
 	"time"
 
+	"weavelab.xyz/ethr/client/http"
 	"weavelab.xyz/ethr/client/icmp"
 	"weavelab.xyz/ethr/client/tcp"
 	"weavelab.xyz/ethr/client/tools"
 	"weavelab.xyz/ethr/client/udp"
 	"weavelab.xyz/ethr/ethr"
 	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
 	"weavelab.xyz/ethr/stats"
 )
 
@@ -24,6 +26,7 @@ type Client struct {
 	TCPTests  tcp.Tests
 	ICMPTests icmp.Tests
 	UDPTests  udp.Tests
+	HTTPTests http.Tests
 
 	NetTools *tools.Tools
 
@@ -40,7 +43,8 @@ func NewClient(isExternal bool, logger ethr.Logger, params ethr.ClientParams, rI
 	return &Client{
 		NetTools:  tools,
 		TCPTests:  tcp.Tests{NetTools: tools, Logger: logger},
-		UDPTests:  udp.Tests{NetTools: tools},
+		UDPTests:  udp.Tests{NetTools: tools, Logger: logger},
+		HTTPTests: http.Tests{NetTools: tools, Logger: logger},
 		ICMPTests: icmp.Tests{NetTools: tools, Logger: logger},
 		Params:    params,
 		Logger:    logger,
@@ -59,18 +63,43 @@ func (c Client) CreateTest(protocol ethr.Protocol, tt ethr.TestType) (*session.T
 			aggregator = tcp.LatencyAggregator
 		case ethr.TestTypePing:
 			aggregator = tcp.PingAggregator
+		case ethr.TestTypeRequestResponse, ethr.TestTypeConnectRequestResponse:
+			aggregator = tcp.TransactionsAggregator
+		case ethr.TestTypeMaxConnections:
+			aggregator = tcp.MaxConnectionsAggregator
 		default:
 			// no aggregator for traceroute (single result w/ pointer updates for mtr)
 		}
 	} else if protocol == ethr.UDP {
 		if tt == ethr.TestTypeBandwidth || tt == ethr.TestTypePacketsPerSecond {
 			aggregator = udp.BandwidthAggregator
+		} else if tt == ethr.TestTypeLatency {
+			aggregator = udp.LatencyAggregator
 		}
 	} else if protocol == ethr.ICMP {
 		if tt == ethr.TestTypePing {
 			aggregator = icmp.PingAggregator
 		}
 
+	} else if protocol == ethr.HTTP {
+		// HTTP results use the same payloads as their TCP counterparts
+		switch tt {
+		case ethr.TestTypeBandwidth:
+			aggregator = tcp.BandwidthAggregator
+		case ethr.TestTypeConnectionsPerSecond:
+			aggregator = tcp.ConnectionsAggregator
+		case ethr.TestTypeLatency:
+			aggregator = tcp.LatencyAggregator
+		}
+	} else if protocol == ethr.HTTPS {
+		switch tt {
+		case ethr.TestTypeBandwidth:
+			aggregator = tcp.BandwidthAggregator
+		case ethr.TestTypeConnectionsPerSecond:
+			aggregator = tcp.ConnectionsAggregator
+		case ethr.TestTypeLatency:
+			aggregator = http.TLSHandshakeAggregator
+		}
 	}
 
 	c.Logger.Info("Using destination: %s, port: %d", c.NetTools.RemoteIP, c.NetTools.RemotePort)
@@ -84,7 +113,7 @@ func (c Client) CreateTest(protocol ethr.Protocol, tt ethr.TestType) (*session.T
 }
 
 func (c Client) RunTest(ctx context.Context, test *session.Test) error {
-	defer close(test.Results)
+	defer test.CloseResults()
 	stats.StartTimer()
 	gap := test.ClientParam.Gap
 	test.IsActive = true
@@ -99,6 +128,14 @@ func (c Client) RunTest(ctx context.Context, test *session.Test) error {
 			go c.TCPTests.TestConnectionsPerSecond(test)
 		case ethr.TestTypePing:
 			go c.TCPTests.TestPing(test, gap, test.ClientParam.WarmupCount)
+		case ethr.TestTypeLatencyUnderLoad:
+			go c.TCPTests.TestLatencyUnderLoad(test, gap, test.ClientParam.WarmupCount)
+		case ethr.TestTypeRequestResponse:
+			go c.TCPTests.TestRequestResponse(test, false)
+		case ethr.TestTypeConnectRequestResponse:
+			go c.TCPTests.TestRequestResponse(test, true)
+		case ethr.TestTypeMaxConnections:
+			go c.TCPTests.TestMaxConnections(test)
 		case ethr.TestTypeTraceRoute:
 			if !c.NetTools.IsAdmin() {
 				return fmt.Errorf("must be admin to run traceroute: %w", ErrPermission)
@@ -118,6 +155,18 @@ func (c Client) RunTest(ctx context.Context, test *session.Test) error {
 			fallthrough
 		case ethr.TestTypeBandwidth:
 			c.UDPTests.TestBandwidth(test)
+		case ethr.TestTypeLatency:
+			go c.UDPTests.TestLatency(test, gap)
+		case ethr.TestTypeTraceRoute:
+			if !c.NetTools.IsAdmin() {
+				return fmt.Errorf("must be admin to run traceroute: %w", ErrPermission)
+			}
+			go c.UDPTests.TestTraceRoute(test, gap, false, 30)
+		case ethr.TestTypeMyTraceRoute:
+			if !c.NetTools.IsAdmin() {
+				return fmt.Errorf("must be admin to run mytraceroute: %w", ErrPermission)
+			}
+			go c.UDPTests.TestTraceRoute(test, gap, true, 30)
 		default:
 			return ErrNotImplemented
 		}
@@ -133,6 +182,30 @@ func (c Client) RunTest(ctx context.Context, test *session.Test) error {
 			go c.ICMPTests.TestTraceRoute(test, gap, false, 16) // normal traceroute defaults to 64
 		case ethr.TestTypeMyTraceRoute:
 			go c.ICMPTests.TestTraceRoute(test, gap, true, 16) // normal traceroute defaults to 64
+		case ethr.TestTypeMTU:
+			go c.ICMPTests.TestMTU(test)
+		default:
+			return ErrNotImplemented
+		}
+	} else if test.ID.Protocol == ethr.HTTP {
+		switch test.ID.Type {
+		case ethr.TestTypeBandwidth:
+			go c.HTTPTests.TestBandwidth(test)
+		case ethr.TestTypeLatency:
+			go c.HTTPTests.TestLatency(test, gap)
+		case ethr.TestTypeConnectionsPerSecond:
+			go c.HTTPTests.TestRequestsPerSecond(test)
+		default:
+			return ErrNotImplemented
+		}
+	} else if test.ID.Protocol == ethr.HTTPS {
+		switch test.ID.Type {
+		case ethr.TestTypeBandwidth:
+			go c.HTTPTests.TestBandwidth(test)
+		case ethr.TestTypeLatency:
+			go c.HTTPTests.TestHandshakeLatency(test, gap)
+		case ethr.TestTypeConnectionsPerSecond:
+			go c.HTTPTests.TestRequestsPerSecond(test)
 		default:
 			return ErrNotImplemented
 		}
@@ -140,8 +213,19 @@ func (c Client) RunTest(ctx context.Context, test *session.Test) error {
 		return ErrNotImplemented
 	}
 
+	if test.ClientParam.Profile.Kind != ethr.ProfileConstant {
+		go publishTargetRate(test)
+	}
+
+	// let tests collect their final results once they are done
+	defer test.Wrapup.Wait()
+
 	//backwards compat with Duration param
 	testComplete := time.After(test.ClientParam.Duration)
+	if test.ID.Type == ethr.TestTypeLatencyUnderLoad {
+		// the test ends itself once all of its phases are done
+		testComplete = nil
+	}
 	select {
 	case <-testComplete:
 		stats.StopTimer()
@@ -165,3 +249,30 @@ func (c Client) RunTest(ctx context.Context, test *session.Test) error {
 		return nil
 	}
 }
+
+// publishTargetRate samples the rate the traffic profile of the test aims at ten
+// times a second, the intervals report its average. Streams not sharing their
+// rate each aim at the rate of the profile.
+func publishTargetRate(test *session.Test) {
+	streams := uint64(test.ClientParam.NumThreads)
+	if test.ClientParam.SharedRate || streams == 0 {
+		streams = 1
+	}
+	start := time.Now()
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-test.Done:
+			return
+		case now := <-ticker.C:
+			test.AddIntermediateResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body: payloads.TargetRatePayload{
+					Rate: streams * test.ClientParam.Profile.RateAt(now.Sub(start), test.ClientParam.BwRate),
+				},
+			})
+		}
+	}
+}
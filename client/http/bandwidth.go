@@ -0,0 +1,127 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+	"weavelab.xyz/ethr/stats"
+)
+
+func (t Tests) TestBandwidth(test *session.Test) {
+	if test.ID.Protocol == ethr.HTTPS {
+		t.testTLSBandwidth(test)
+		return
+	}
+	client := t.newClient(test, nil)
+	limiters := threadLimiters(test)
+	for th := uint32(0); th < test.ClientParam.NumThreads; th++ {
+		go t.runBandwidth(test, client, strconv.Itoa(int(th)), limiters[th])
+	}
+}
+
+// threadLimiters returns the limiter of each thread, one shared by all of them
+// when the test shares its rate.
+func threadLimiters(test *session.Test) []*stats.Limiter {
+	p := test.ClientParam
+	limiters := make([]*stats.Limiter, p.NumThreads)
+	for th := range limiters {
+		if th == 0 || !p.SharedRate {
+			limiters[th] = stats.NewLimiter(p)
+		} else {
+			limiters[th] = limiters[0]
+		}
+	}
+	return limiters
+}
+
+// testTLSBandwidth spreads the threads across the usable TLS profiles, results are
+// reported per profile instead of per thread.
+func (t Tests) testTLSBandwidth(test *session.Test) {
+	profiles := t.usableTLSProfiles(test)
+	if len(profiles) == 0 {
+		t.Logger.Error("No TLS profile accepted by server %s", test.DialAddr)
+		return
+	}
+	if int(test.ClientParam.NumThreads) < len(profiles) {
+		t.Logger.Info("Only %d of %d TLS profiles are tested, use -n to run more threads", test.ClientParam.NumThreads, len(profiles))
+	}
+
+	clients := make([]*http.Client, len(profiles))
+	for i, p := range profiles {
+		clients[i] = t.newClient(test, p.config(test, nil))
+	}
+	limiters := threadLimiters(test)
+	for th := 0; th < int(test.ClientParam.NumThreads); th++ {
+		i := th % len(profiles)
+		go t.runBandwidth(test, clients[i], profiles[i].String(), limiters[th])
+	}
+}
+
+// runBandwidth uploads request bodies of BufferSize bytes, or downloads response
+// bodies of that size in reverse mode, until the test is done.
+func (t Tests) runBandwidth(test *session.Test, client *http.Client, id string, limiter *stats.Limiter) {
+	size := test.ClientParam.BufferSize
+	buff := make([]byte, size)
+	for i := uint32(0); i < size; i++ {
+		buff[i] = byte(i)
+	}
+	readBuff := make([]byte, 64*1024)
+	var backoff ethr.Backoff
+	failed := 0
+	for {
+		select {
+		case <-test.Done:
+			if failed > 0 {
+				t.Logger.Info("%d HTTP requests of %s failed", failed, id)
+			}
+			return
+		default:
+			bytesToSend := limiter.Limit(len(buff))
+			if !limiter.Wait(test.Done, bytesToSend) {
+				continue
+			}
+			var resp *http.Response
+			var err error
+			if test.ClientParam.Reverse {
+				resp, err = client.Get(t.url(test, uint32(bytesToSend)))
+			} else {
+				resp, err = client.Post(t.url(test, 0), "application/octet-stream", bytes.NewReader(buff[:bytesToSend]))
+			}
+			if err != nil {
+				t.Logger.Debug("error sending HTTP request for bandwidth test: %v", err)
+				failed++
+				backoff.Wait(test.Done)
+				continue
+			}
+			n, err := io.CopyBuffer(ioutil.Discard, resp.Body, readBuff)
+			_ = resp.Body.Close()
+			if err != nil || resp.StatusCode != http.StatusOK {
+				t.Logger.Debug("error receiving HTTP response for bandwidth test: %v (status %d)", err, resp.StatusCode)
+				failed++
+				backoff.Wait(test.Done)
+				continue
+			}
+			backoff.Reset()
+			if !test.ClientParam.Reverse {
+				n = int64(bytesToSend)
+			}
+
+			test.AddIntermediateResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body: payloads.RawBandwidthPayload{
+					ConnectionID:     id,
+					Bandwidth:        uint64(n),
+					PacketsPerSecond: 1,
+				},
+			})
+
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// TestHandshakeLatency measures full and resumed TLS handshakes for every TLS
+// profile accepted by the server. The TCP connect is not part of the measurement.
+func (t Tests) TestHandshakeLatency(test *session.Test, g time.Duration) {
+	profiles := t.usableTLSProfiles(test)
+	if len(profiles) == 0 {
+		t.Logger.Error("No TLS profile accepted by server %s", test.DialAddr)
+		return
+	}
+	caches := make([]tls.ClientSessionCache, len(profiles))
+	for i := range profiles {
+		caches[i] = tls.NewLRUClientSessionCache(1)
+	}
+
+	rttCount := int(test.ClientParam.RttCount)
+	for {
+	ExitSelect:
+		select {
+		case <-test.Done:
+			return
+		default:
+			t0 := time.Now()
+			for i, p := range profiles {
+				raw := payloads.RawTLSHandshakes{
+					Full:    make([]time.Duration, 0, rttCount),
+					Resumed: make([]time.Duration, 0, rttCount),
+				}
+				for n := 0; n < rttCount; n++ {
+					d, state, err := t.timeHandshake(test, p.config(test, nil))
+					if err != nil {
+						test.AddDirectResult(session.TestResult{
+							Success: false,
+							Error:   fmt.Errorf("full handshake for %s failed: %w", p, err),
+							Body:    nil,
+						})
+						break ExitSelect
+					}
+					raw.Profile = TLSVersionName(state.Version) + "/" + shortSuiteName(state.CipherSuite)
+					raw.Full = append(raw.Full, d)
+
+					d, state, err = t.timeHandshake(test, p.config(test, caches[i]))
+					if err != nil {
+						test.AddDirectResult(session.TestResult{
+							Success: false,
+							Error:   fmt.Errorf("resumed handshake for %s failed: %w", p, err),
+							Body:    nil,
+						})
+						break ExitSelect
+					}
+					// the first connection only primes the session cache
+					if state.DidResume {
+						raw.Resumed = append(raw.Resumed, d)
+					}
+				}
+				test.AddIntermediateResult(session.TestResult{
+					Success: true,
+					Error:   nil,
+					Body:    raw,
+				})
+			}
+
+			t1 := time.Since(t0)
+			if t1 < g {
+				time.Sleep(g - t1)
+			}
+		}
+	}
+}
+
+// timeHandshake returns the duration of the handshake alone. When the config has a
+// session cache a request is completed as well, TLS 1.3 servers only send session
+// tickets after the handshake.
+func (t Tests) timeHandshake(test *session.Test, cfg *tls.Config) (time.Duration, tls.ConnectionState, error) {
+	conn, err := t.NetTools.Dial(ethr.TCP, test.DialAddr, t.NetTools.LocalIP, 0, 0, 0)
+	if err != nil {
+		return 0, tls.ConnectionState{}, fmt.Errorf("unable to dial TCP connection to %s: %w", test.DialAddr, err)
+	}
+	defer conn.Close()
+
+	tlsConn := tls.Client(conn, cfg)
+	t0 := time.Now()
+	err = tlsConn.Handshake()
+	elapsed := time.Since(t0)
+	if err != nil {
+		return 0, tls.ConnectionState{}, err
+	}
+
+	if cfg.ClientSessionCache != nil {
+		_, err = fmt.Fprintf(tlsConn, "GET / HTTP/1.1\r\nHost: %s\r\nConnection: close\r\n\r\n", test.DialAddr)
+		if err == nil {
+			_, err = io.Copy(ioutil.Discard, tlsConn)
+		}
+		if err != nil {
+			return 0, tls.ConnectionState{}, fmt.Errorf("request after handshake failed: %w", err)
+		}
+	}
+	return elapsed, tlsConn.ConnectionState(), nil
+}
+
+func TLSHandshakeAggregator(nanos uint64, intermediateResults []session.TestResult) session.TestResult {
+	full := make(map[string][]time.Duration)
+	resumed := make(map[string][]time.Duration)
+	for _, r := range intermediateResults {
+		// ignore failed results
+		if body, ok := r.Body.(payloads.RawTLSHandshakes); ok && r.Success {
+			full[body.Profile] = append(full[body.Profile], body.Full...)
+			resumed[body.Profile] = append(resumed[body.Profile], body.Resumed...)
+		}
+	}
+
+	handshakes := make([]payloads.TLSHandshakePayload, 0, len(full))
+	for profile, latencies := range full {
+		handshakes = append(handshakes, payloads.TLSHandshakePayload{
+			Profile: profile,
+			Full:    payloads.NewLatencies(latencies),
+			Resumed: payloads.NewLatencies(resumed[profile]),
+		})
+	}
+	sort.SliceStable(handshakes, func(i, j int) bool {
+		return handshakes[i].Profile < handshakes[j].Profile
+	})
+
+	return session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body:    payloads.TLSPayload{Handshakes: handshakes},
+	}
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// TestLatency measures the time taken by a request carrying BufferSize bytes to be
+// answered with a response of the same size.
+func (t Tests) TestLatency(test *session.Test, g time.Duration) {
+	client := t.newClient(test, nil)
+	buffSize := test.ClientParam.BufferSize
+	buff := make([]byte, buffSize)
+	for i := uint32(0); i < buffSize; i++ {
+		buff[i] = byte(i)
+	}
+	url := t.url(test, buffSize)
+	rttCount := test.ClientParam.RttCount
+	latencyNumbers := make([]time.Duration, rttCount)
+	for {
+	ExitSelect:
+		select {
+		case <-test.Done:
+			return
+		default:
+			t0 := time.Now()
+			for i := uint32(0); i < rttCount; i++ {
+				s1 := time.Now()
+				resp, err := client.Post(url, "application/octet-stream", bytes.NewReader(buff))
+				if err != nil {
+					test.AddDirectResult(session.TestResult{
+						Success: false,
+						Error:   fmt.Errorf("error sending HTTP request: %w", err),
+						Body:    nil,
+					})
+					break ExitSelect
+				}
+				_, err = io.Copy(ioutil.Discard, resp.Body)
+				_ = resp.Body.Close()
+				if err != nil || resp.StatusCode != http.StatusOK {
+					test.AddDirectResult(session.TestResult{
+						Success: false,
+						Error:   fmt.Errorf("error receiving HTTP response (status %d): %w", resp.StatusCode, err),
+						Body:    nil,
+					})
+					break ExitSelect
+				}
+				latencyNumbers[i] = time.Since(s1)
+			}
+
+			test.AddIntermediateResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body: payloads.RawLatencies{
+					Latencies: latencyNumbers,
+				},
+			})
+			t1 := time.Since(t0)
+			if t1 < g {
+				time.Sleep(g - t1)
+			}
+		}
+	}
+}
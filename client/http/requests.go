@@ -0,0 +1,65 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// TestRequestsPerSecond sends empty GET requests from NumThreads goroutines, it is
+// the HTTP flavor of the connections/s test.
+func (t Tests) TestRequestsPerSecond(test *session.Test) {
+	client := t.newClient(test, tlsConfig(test))
+	url := t.url(test, 0)
+	for th := uint32(0); th < test.ClientParam.NumThreads; th++ {
+		go func() {
+			var backoff ethr.Backoff
+			for {
+				select {
+				case <-test.Done:
+					return
+				default:
+					err := t.request(client, url)
+					if err != nil {
+						t.Logger.Debug("HTTP request to %s failed: %v", url, err)
+						test.AddIntermediateResult(session.TestResult{
+							Success: false,
+							Error:   err,
+							Body:    payloads.ConnectionsPerSecondPayload{Failed: 1},
+						})
+						// a server that is down or refusing fails right away
+						backoff.Wait(test.Done)
+						continue
+					}
+					backoff.Reset()
+					test.AddIntermediateResult(session.TestResult{
+						Success: true,
+						Error:   nil,
+						Body:    payloads.ConnectionsPerSecondPayload{Connections: 1},
+					})
+				}
+			}
+		}()
+	}
+}
+
+func (t Tests) request(client *http.Client, url string) error {
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
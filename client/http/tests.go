@@ -0,0 +1,51 @@
+package http
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"weavelab.xyz/ethr/client/tools"
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+)
+
+type Tests struct {
+	NetTools *tools.Tools
+	Logger   ethr.Logger
+}
+
+// newClient creates an HTTP client dialing through the ethr network tools, one
+// idle connection is kept per thread unless keep-alive is disabled. tlsConfig is
+// only used by HTTPS tests.
+func (t Tests) newClient(test *session.Test, tlsConfig *tls.Config) *http.Client {
+	transport := &http.Transport{
+		TLSClientConfig: tlsConfig,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return t.NetTools.Dial(ethr.TCP, addr, t.NetTools.LocalIP, 0, 0, 0)
+		},
+		DisableKeepAlives:   test.ClientParam.NoKeepAlive,
+		DisableCompression:  true,
+		MaxIdleConnsPerHost: int(test.ClientParam.NumThreads),
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   10 * time.Second,
+	}
+}
+
+func (t Tests) url(test *session.Test, size uint32) string {
+	scheme := "http"
+	if test.ID.Protocol == ethr.HTTPS {
+		scheme = "https"
+	}
+	host := net.JoinHostPort(test.RemoteIP.String(), strconv.Itoa(int(test.RemotePort)))
+	if size == 0 {
+		return fmt.Sprintf("%s://%s/", scheme, host)
+	}
+	return fmt.Sprintf("%s://%s/?size=%d", scheme, host, size)
+}
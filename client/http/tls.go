@@ -0,0 +1,178 @@
+package http
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+)
+
+var defaultTLSVersions = []uint16{tls.VersionTLS12, tls.VersionTLS13}
+
+// tlsProfile pins the TLS version and, before TLS 1.3, the cipher suite used by
+// a connection so results can be broken out per profile.
+type tlsProfile struct {
+	version uint16
+	suite   uint16 // zero lets TLS 1.3 negotiate the suite
+}
+
+func (p tlsProfile) String() string {
+	if p.suite == 0 {
+		return TLSVersionName(p.version)
+	}
+	return TLSVersionName(p.version) + "/" + shortSuiteName(p.suite)
+}
+
+// tlsProfiles expands the versions and cipher suites requested by the client into
+// the list of profiles to test.
+func tlsProfiles(params ethr.ClientParams) []tlsProfile {
+	versions := params.TLSVersions
+	if len(versions) == 0 {
+		versions = defaultTLSVersions
+	}
+	profiles := make([]tlsProfile, 0)
+	for _, v := range versions {
+		if v == tls.VersionTLS13 {
+			// TLS 1.3 cipher suites are not configurable
+			profiles = append(profiles, tlsProfile{version: v})
+			continue
+		}
+		for _, s := range tls.CipherSuites() {
+			if !supportsVersion(s, v) || !wantSuite(params.CipherSuites, s.ID) {
+				continue
+			}
+			profiles = append(profiles, tlsProfile{version: v, suite: s.ID})
+		}
+	}
+	return profiles
+}
+
+// usableTLSProfiles performs a handshake for every profile and drops the ones the
+// server does not accept, e.g. RSA suites against an ECDSA certificate.
+func (t Tests) usableTLSProfiles(test *session.Test) []tlsProfile {
+	usable := make([]tlsProfile, 0)
+	for _, p := range tlsProfiles(test.ClientParam) {
+		conn, err := t.dialTLS(test, p.config(test, nil))
+		if err != nil {
+			t.Logger.Info("Skipping TLS profile %s: %v", p, err)
+			continue
+		}
+		_ = conn.Close()
+		usable = append(usable, p)
+	}
+	return usable
+}
+
+func (p tlsProfile) config(test *session.Test, cache tls.ClientSessionCache) *tls.Config {
+	cfg := tlsConfig(test)
+	cfg.MinVersion = p.version
+	cfg.MaxVersion = p.version
+	if p.suite != 0 {
+		cfg.CipherSuites = []uint16{p.suite}
+	}
+	cfg.ClientSessionCache = cache
+	return cfg
+}
+
+// tlsConfig accepts certificates chaining to a system root, or anything when
+// IgnoreCert is set.
+func tlsConfig(test *session.Test) *tls.Config {
+	host := test.RemoteIP.String()
+	cfg := &tls.Config{
+		ServerName:         host,
+		InsecureSkipVerify: true,
+	}
+	if test.ClientParam.IgnoreCert {
+		return cfg
+	}
+	cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		return verifyServerCert(host, rawCerts)
+	}
+	return cfg
+}
+
+func verifyServerCert(host string, rawCerts [][]byte) error {
+	if len(rawCerts) == 0 {
+		return errors.New("server did not present a certificate")
+	}
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse server certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	leaf := certs[0]
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates})
+	if err != nil {
+		return fmt.Errorf("invalid server certificate (use -ic to ignore): %w", err)
+	}
+	return nil
+}
+
+func (t Tests) dialTLS(test *session.Test, cfg *tls.Config) (*tls.Conn, error) {
+	conn, err := t.NetTools.Dial(ethr.TCP, test.DialAddr, t.NetTools.LocalIP, 0, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("unable to dial TCP connection to %s: %w", test.DialAddr, err)
+	}
+	tlsConn := tls.Client(conn, cfg)
+	err = tlsConn.Handshake()
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("tls handshake failed: %w", err)
+	}
+	return tlsConn, nil
+}
+
+func supportsVersion(s *tls.CipherSuite, version uint16) bool {
+	for _, v := range s.SupportedVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+func wantSuite(suites []uint16, id uint16) bool {
+	if len(suites) == 0 {
+		return true
+	}
+	for _, s := range suites {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
+
+// TLSVersionName returns the short name of a TLS version, e.g. "TLS1.2".
+func TLSVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLS1.0"
+	case tls.VersionTLS11:
+		return "TLS1.1"
+	case tls.VersionTLS12:
+		return "TLS1.2"
+	case tls.VersionTLS13:
+		return "TLS1.3"
+	default:
+		return fmt.Sprintf("0x%04x", version)
+	}
+}
+
+func shortSuiteName(id uint16) string {
+	name := strings.TrimPrefix(tls.CipherSuiteName(id), "TLS_")
+	name = strings.Replace(name, "_WITH_", "_", 1)
+	return strings.Replace(name, "_", "-", -1)
+}
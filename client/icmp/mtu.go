@@ -0,0 +1,252 @@
+package icmp
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// Every size is probed mtuAttempts times before it counts as lost, as routers
+// limit the rate of their ICMP errors.
+const (
+	mtuProbeTimeout = time.Second
+	mtuAttempts     = 2
+	mtuMaxHops      = 30
+	maxPacketSize   = 65535
+)
+
+type replyKind int
+
+const (
+	replyNone replyKind = iota
+	replyEcho
+	replyTooBig
+	replyTimeExceeded
+)
+
+type mtuReply struct {
+	kind replyKind
+	peer net.Addr
+	mtu  int
+}
+
+// mtuProber sends echo requests of a given size with the DF bit set.
+type mtuProber struct {
+	conn     net.PacketConn
+	dest     net.Addr
+	id       int
+	seq      int
+	overhead int
+}
+
+// TestMTU binary searches the biggest echo request reaching the destination
+// without fragmentation, then looks for the hop dropping bigger packets.
+func (t Tests) TestMTU(test *session.Test) {
+	mtu, err := t.findMTU(test)
+	if err != nil {
+		test.AddDirectResult(session.TestResult{
+			Success: false,
+			Error:   err,
+			Body:    nil,
+		})
+	} else {
+		test.AddDirectResult(session.TestResult{
+			Success: true,
+			Error:   nil,
+			Body:    mtu,
+		})
+	}
+	test.Terminate()
+}
+
+func (t Tests) findMTU(test *session.Test) (payloads.MTUPayload, error) {
+	conn, err := t.NetTools.IcmpNewConn(test.RemoteIP.String())
+	if err != nil {
+		return payloads.MTUPayload{}, fmt.Errorf("failed to create icmp connection: %w", err)
+	}
+	defer conn.Close()
+	err = t.NetTools.SetDontFragment(conn)
+	if err != nil {
+		return payloads.MTUPayload{}, fmt.Errorf("failed to set the don't fragment bit: %w", err)
+	}
+
+	p := &mtuProber{
+		conn:     conn,
+		dest:     &net.IPAddr{IP: test.RemoteIP},
+		id:       os.Getpid() & 0xffff,
+		overhead: ipv4.HeaderLen + 8,
+	}
+	// smallest MTU every link has to support
+	good := 576
+	if t.NetTools.IPVersion == ethr.IPv6 {
+		p.overhead = ipv6.HeaderLen + 8
+		good = 1280
+	}
+	bad, err := t.NetTools.InterfaceMTU(test.RemoteIP)
+	if err != nil {
+		return payloads.MTUPayload{}, err
+	}
+	if bad > maxPacketSize {
+		bad = maxPacketSize
+	}
+
+	if t.probeSize(test, p, good).Result != payloads.MTUProbeFits {
+		return payloads.MTUPayload{}, fmt.Errorf("destination (%s) not responding to ICMP echo", test.RemoteIP)
+	}
+	last := t.probeSize(test, p, bad)
+	if last.Result == payloads.MTUProbeFits {
+		return payloads.MTUPayload{MTU: bad, HopNumber: -1}, nil
+	}
+	for bad-good > 1 {
+		select {
+		case <-test.Done:
+			return payloads.MTUPayload{}, fmt.Errorf("test ended before the path MTU was found, it is between %d and %d", good, bad-1)
+		default:
+		}
+		size := (good + bad) / 2
+		// routers usually know the MTU of the next link
+		if last.NextHopMTU > good && last.NextHopMTU < bad {
+			size = last.NextHopMTU
+		}
+		last = t.probeSize(test, p, size)
+		if last.Result == payloads.MTUProbeFits {
+			good = size
+		} else {
+			bad = size
+		}
+	}
+
+	result := payloads.MTUPayload{MTU: good, HopNumber: -1}
+	result.LimitingHop, result.HopNumber, result.BlackHole = t.locateMTULimit(test, p, good+1)
+	return result, nil
+}
+
+// probeSize tells whether packets of the given size reach the destination.
+func (t Tests) probeSize(test *session.Test, p *mtuProber, size int) payloads.MTUProbe {
+	probe := payloads.MTUProbe{Size: size, Result: payloads.MTUProbeLost}
+	for attempt := 0; attempt < mtuAttempts; attempt++ {
+		reply := t.sendProbe(p, size, 64)
+		if reply.kind == replyEcho {
+			probe.Result = payloads.MTUProbeFits
+			break
+		}
+		if reply.kind == replyTooBig {
+			probe.Result, probe.Peer, probe.NextHopMTU = payloads.MTUProbeTooBig, reply.peer, reply.mtu
+			break
+		}
+	}
+	test.AddDirectResult(session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body:    probe,
+	})
+	return probe
+}
+
+// locateMTULimit sends packets too big for the path with increasing TTL. Hops
+// before the limit answer with Time Exceeded, the limiting hop answers with
+// Fragmentation Needed or Packet Too Big, in black holes nothing answers.
+func (t Tests) locateMTULimit(test *session.Test, p *mtuProber, size int) (net.Addr, int, bool) {
+	var last net.Addr
+	lastHop := -1
+	for ttl := 1; ttl <= mtuMaxHops; ttl++ {
+		select {
+		case <-test.Done:
+			return nil, -1, false
+		default:
+		}
+		var reply mtuReply
+		for attempt := 0; attempt < mtuAttempts && reply.kind == replyNone; attempt++ {
+			reply = t.sendProbe(p, size, ttl)
+		}
+		switch reply.kind {
+		case replyTimeExceeded:
+			last, lastHop = reply.peer, ttl-1
+		case replyTooBig:
+			return reply.peer, ttl - 2, false
+		case replyNone:
+			// hops that never answer don't drop packets for their size
+			silent := t.sendProbe(p, p.overhead, ttl)
+			if silent.kind == replyTimeExceeded || silent.kind == replyEcho {
+				return last, lastHop, true
+			}
+		default:
+			return nil, -1, false
+		}
+	}
+	return nil, -1, false
+}
+
+func (t Tests) sendProbe(p *mtuProber, size int, ttl int) mtuReply {
+	p.seq = (p.seq + 1) & 0xffff
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho,
+		Code: 0,
+		Body: &icmp.Echo{
+			ID:   p.id,
+			Seq:  p.seq,
+			Data: make([]byte, size-p.overhead),
+		},
+	}
+	if t.NetTools.IPVersion == ethr.IPv6 {
+		msg.Type = ipv6.ICMPTypeEchoRequest
+	}
+	err := t.NetTools.SendICMP(p.conn, p.dest, ttl, mtuProbeTimeout, &msg)
+	if err != nil {
+		t.Logger.Debug("Failed to send %d byte probe: %v", size, err)
+		return mtuReply{}
+	}
+
+	deadline := time.Now().Add(mtuProbeTimeout)
+	for time.Now().Before(deadline) {
+		resp, peer, err := t.NetTools.ReceiveICMPFromPeer(p.conn, time.Until(deadline), "")
+		if err != nil {
+			return mtuReply{}
+		}
+		switch body := resp.Body.(type) {
+		case *icmp.Echo:
+			if (resp.Type == ipv4.ICMPTypeEchoReply || resp.Type == ipv6.ICMPTypeEchoReply) && body.ID == p.id && body.Seq == p.seq {
+				return mtuReply{kind: replyEcho, peer: peer}
+			}
+		case *icmp.DstUnreach:
+			// code 4 is Fragmentation Needed
+			if resp.Code == 4 && t.quotesProbe(body.Data, p) {
+				return mtuReply{kind: replyTooBig, peer: peer}
+			}
+		case *icmp.PacketTooBig:
+			if t.quotesProbe(body.Data, p) {
+				return mtuReply{kind: replyTooBig, peer: peer, mtu: body.MTU}
+			}
+		case *icmp.TimeExceeded:
+			if t.quotesProbe(body.Data, p) {
+				return mtuReply{kind: replyTimeExceeded, peer: peer}
+			}
+		}
+	}
+	return mtuReply{}
+}
+
+// quotesProbe checks the packet quoted by an ICMP error is the last probe.
+func (t Tests) quotesProbe(quoted []byte, p *mtuProber) bool {
+	headerLen := ipv6.HeaderLen
+	if t.NetTools.IPVersion != ethr.IPv6 {
+		if len(quoted) < ipv4.HeaderLen {
+			return false
+		}
+		headerLen = int(quoted[0]&0x0f) << 2
+	}
+	if len(quoted) < headerLen+8 {
+		return false
+	}
+	echo := quoted[headerLen:]
+	return int(echo[4])<<8|int(echo[5]) == p.id && int(echo[6])<<8|int(echo[7]) == p.seq
+}
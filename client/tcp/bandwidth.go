@@ -4,6 +4,8 @@ import (
 	"net"
 	"sort"
 	"strconv"
+	"sync/atomic"
+	"time"
 
 	"weavelab.xyz/ethr/session/payloads"
 
@@ -14,71 +16,244 @@ import (
 )
 
 func (t Tests) TestBandwidth(test *session.Test) {
+	meter := &cpuMeter{mode: test.ClientParam.SendMode}
+	if err := meter.start(); err != nil {
+		t.Logger.Debug("Failed to read CPU time, its cost won't be reported: %v", err)
+	} else {
+		test.Wrapup.Add(1)
+		go t.reportCPUCost(test, meter)
+	}
+	var sizes *sizeMeter
+	if test.ClientParam.Sizes.IsSet() && !test.ClientParam.Reverse {
+		sizes = newSizeMeter()
+		test.Wrapup.Add(1)
+		go t.reportSizes(test, sizes)
+	}
+	var integrity *integrityMeter
+	if test.ClientParam.Verify {
+		integrity = newIntegrityMeter()
+		test.Wrapup.Add(1)
+		go t.reportIntegrity(test, integrity)
+	}
+	var shared *stats.Limiter
+	if test.ClientParam.SharedRate {
+		shared = stats.NewLimiter(test.ClientParam)
+	}
 	for th := uint32(0); th < test.ClientParam.NumThreads; th++ {
-		conn, err := t.NetTools.Dial(ethr.TCP, test.DialAddr, t.NetTools.LocalIP, t.NetTools.LocalPort+uint16(th), 0, 0) // referenced gTTL and gTOS which were never modified
+		conn, err := t.NetTools.DialStream(ethr.TCP, test.DialAddr, t.NetTools.LocalIP, t.NetTools.LocalPort+uint16(th), 0, 0, test.ClientParam.ForStream(int(th))) // referenced gTTL and gTOS which were never modified
 		if err != nil {
+			t.Logger.Error("Failed to dial stream %d: %v", th, err)
 			continue
 		}
-		err = test.Session.HandshakeWithServer(test, conn)
+		server, err := test.Session.HandshakeWithServerStream(test, int(th), conn)
 		if err != nil {
+			t.Logger.Error("Failed in handshake of stream %d: %v", th, err)
 			_ = conn.Close()
 			continue
 		}
-		go t.handleBandwidthConn(test, conn, strconv.Itoa(int(th)))
+		t.reportStreamOptions(test, conn, strconv.Itoa(int(th)), server)
+		if integrity != nil && !test.ClientParam.Reverse && !test.ClientParam.Bidirectional {
+			integrity.pending.Add(1)
+		}
+		if !test.ClientParam.Reverse {
+			meter.senders.Add(1)
+		}
+		go t.handleBandwidthConn(test, conn, int(th), shared, meter, sizes, integrity)
 	}
 }
 
-func (t Tests) handleBandwidthConn(test *session.Test, conn net.Conn, id string) {
+// handleBandwidthConn sends, receives or, for bidirectional tests, does both at
+// once on the connection of stream th until the test is done. shared limits the
+// streams of tests sharing their rate, sizes counts the messages sent by size
+// and integrity, of tests verifying the payload, what was verified of it.
+func (t Tests) handleBandwidthConn(test *session.Test, conn net.Conn, th int, shared *stats.Limiter, meter *cpuMeter, sizes *sizeMeter, integrity *integrityMeter) {
 	defer conn.Close()
 
-	size := test.ClientParam.BufferSize
-	buff := make([]byte, size)
-	for i := uint32(0); i < size; i++ {
-		buff[i] = byte(i)
+	id := strconv.Itoa(th)
+	var verifier *ethr.StreamVerifier
+	if integrity != nil && (test.ClientParam.Bidirectional || test.ClientParam.Reverse) {
+		verifier = integrity.verifier(id, test.ClientParam.ForStream(th).VerifySeed)
+	}
+	if test.ClientParam.Bidirectional {
+		go t.sampleTCPInfo(test, conn, id, ethr.Upstream)
+		go t.sendBandwidth(test, conn, th, shared, meter, sizes)
+		t.receiveBandwidth(test, conn, id, meter, verifier)
+	} else if test.ClientParam.Reverse {
+		go t.sampleTCPInfo(test, conn, id, ethr.Downstream)
+		t.receiveBandwidth(test, conn, id, meter, verifier)
+	} else if integrity != nil {
+		go t.sampleTCPInfo(test, conn, id, ethr.Upstream)
+		go func() {
+			// a send blocked when the test is done mustn't hold up the report
+			<-test.Done
+			_ = conn.SetWriteDeadline(time.Now().Add(integrityTimeout))
+		}()
+		t.sendBandwidth(test, conn, th, shared, meter, sizes)
+		t.receiveIntegrity(test, conn, id, integrity)
+	} else {
+		go t.sampleTCPInfo(test, conn, id, ethr.Upstream)
+		t.sendBandwidth(test, conn, th, shared, meter, sizes)
+	}
+}
+
+func (t Tests) sendBandwidth(test *session.Test, conn net.Conn, th int, shared *stats.Limiter, meter *cpuMeter, sizes *sizeMeter) {
+	defer meter.senders.Done()
+	id := strconv.Itoa(th)
+	buff := make([]byte, test.ClientParam.BufferSize)
+	filler, err := test.ClientParam.Content.NewFiller()
+	if err != nil {
+		t.Logger.Error("Failed to fill the buffer of connection %s: %v", id, err)
+		return
+	}
+	filler.Fill(buff)
+	sender, err := ethr.NewStreamSender(conn, buff, test.ClientParam.SendMode, &meter.sends)
+	if err != nil {
+		t.Logger.Error("Failed to send with %s on connection %s: %v", test.ClientParam.SendMode, id, err)
+		return
+	}
+	defer sender.Close()
+	params := test.ClientParam.ForStream(th)
+	limiter, err := stats.StreamLimiter(params, conn, shared)
+	if err != nil {
+		t.Logger.Error("Failed to pace connection %s in the kernel, pacing it in user space: %v", id, err)
+	}
+	sizer := ethr.NewSizer(params.Sizes, len(buff))
+	var stream *ethr.PayloadStream
+	if params.Verify {
+		stream = ethr.NewPayloadStream(params.VerifySeed)
 	}
-	bufferLen := len(buff)
-	totalBytesToSend := test.ClientParam.BwRate
-	sentBytes := uint64(0)
-	start, waitTime, bytesToSend := stats.BeginThrottle(totalBytesToSend, bufferLen)
 	for {
 		select {
 		case <-test.Done:
 			return
 		default:
-			n := 0
-			var err error = nil
-			if test.ClientParam.Reverse {
-				n, err = conn.Read(buff)
-			} else {
-				n, err = conn.Write(buff[:bytesToSend])
+			bytesToSend := limiter.Limit(sizer.Next())
+			if stream != nil {
+				stream.Fill(buff[:bytesToSend])
+			} else if !filler.Static() {
+				filler.Fill(buff[:bytesToSend])
+			}
+			if !limiter.Wait(test.Done, bytesToSend) {
+				continue
+			}
+			s1 := time.Now()
+			n, err := sender.Send(bytesToSend)
+			if err != nil {
+				//t.Logger.Error("error sending data on a connection for bandwidth test: %w", err)
+				return
 			}
+			sizes.sent(n, time.Since(s1))
+			atomic.AddUint64(&meter.sent, uint64(n))
+
+			test.AddIntermediateResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body: payloads.RawBandwidthPayload{
+					ConnectionID:     id,
+					Direction:        ethr.Upstream,
+					Bandwidth:        uint64(n),
+					PacketsPerSecond: 1,
+				},
+			})
+		}
+	}
+}
+
+// receiveBandwidth receives on the connection until the test is done, checking
+// every byte with verifier when it isn't nil.
+func (t Tests) receiveBandwidth(test *session.Test, conn net.Conn, id string, meter *cpuMeter, verifier *ethr.StreamVerifier) {
+	buff := make([]byte, test.ClientParam.BufferSize)
+	for {
+		select {
+		case <-test.Done:
+			return
+		default:
+			n, err := conn.Read(buff)
 			if err != nil {
-				//t.Logger.Error("error sending/receiving data on a connection for bandwidth test: %w", err)
+				//t.Logger.Error("error receiving data on a connection for bandwidth test: %w", err)
 				return
 			}
+			if verifier != nil && verifier.Check(buff[:n]) {
+				t.Logger.Error("Data received on connection %s is corrupted at offset %d of the stream", id, atomic.LoadUint64(&verifier.FirstCorruption))
+			}
+			atomic.AddUint64(&meter.received, uint64(n))
 
 			test.AddIntermediateResult(session.TestResult{
 				Success: true,
 				Error:   nil,
 				Body: payloads.RawBandwidthPayload{
 					ConnectionID:     id,
+					Direction:        ethr.Downstream,
 					Bandwidth:        uint64(n),
 					PacketsPerSecond: 1,
 				},
 			})
+		}
+	}
+}
 
-			if !test.ClientParam.Reverse {
-				sentBytes += uint64(n)
-				start, waitTime, sentBytes, bytesToSend = stats.EnforceThrottle(start, waitTime, totalBytesToSend, sentBytes, bufferLen)
+// sampleTCPInfo publishes the kernel's state of the connection twice a second so
+// every interval has a sample. Most of it describes the sender, the samples are
+// tagged with the direction the connection sends in.
+func (t Tests) sampleTCPInfo(test *session.Test, conn net.Conn, id string, direction ethr.Direction) {
+	ticker := time.NewTicker(500 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-test.Done:
+			return
+		case <-ticker.C:
+			info, err := t.NetTools.TCPInfo(conn)
+			if err != nil {
+				t.Logger.Debug("Stopped sampling TCP_INFO of connection %s: %v", id, err)
+				return
 			}
+			test.AddIntermediateResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body: payloads.RawBandwidthPayload{
+					ConnectionID: id,
+					Direction:    direction,
+					TCPInfo:      info,
+				},
+			})
 		}
 	}
 }
 
+// reportStreamOptions adds the socket options the kernel granted both ends of the
+// stream to the summaries of tests tuning them.
+func (t Tests) reportStreamOptions(test *session.Test, conn net.Conn, id string, server ethr.StreamOptions) {
+	if !test.ClientParam.HasStreamOptions() {
+		return
+	}
+	options, err := t.NetTools.StreamOptions(conn)
+	if err != nil {
+		t.Logger.Debug("Failed to read socket options of connection %s: %v", id, err)
+		return
+	}
+	test.AddSummary(session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body: payloads.StreamOptionsPayload{
+			ConnectionID: id,
+			Options:      options,
+			Server:       server,
+		},
+	})
+}
+
+type connectionKey struct {
+	id        string
+	direction ethr.Direction
+}
+
 func BandwidthAggregator(nanos uint64, intermediateResults []session.TestResult) session.TestResult {
 	totalBandwidth := uint64(0)
 	totalPackets := uint64(0)
-	connectionAggregates := make(map[string]*payloads.RawBandwidthPayload)
+	targetTotal, targetSamples := uint64(0), uint64(0)
+	connectionAggregates := make(map[connectionKey]*payloads.RawBandwidthPayload)
+	directionAggregates := make(map[ethr.Direction]*payloads.RawBandwidthPayload)
 
 	for _, r := range intermediateResults {
 		// ignore failed results
@@ -86,29 +261,68 @@ func BandwidthAggregator(nanos uint64, intermediateResults []session.TestResult)
 			totalBandwidth += body.Bandwidth
 			totalPackets += body.PacketsPerSecond
 
-			if connection, ok := connectionAggregates[body.ConnectionID]; ok {
+			key := connectionKey{body.ConnectionID, body.Direction}
+			if connection, ok := connectionAggregates[key]; ok {
 				connection.Bandwidth += body.Bandwidth
 				connection.PacketsPerSecond += body.PacketsPerSecond
+				if body.TCPInfo != nil {
+					connection.TCPInfo = body.TCPInfo
+				}
 			} else {
-				connectionAggregates[body.ConnectionID] = &body
+				b := body
+				connectionAggregates[key] = &b
 			}
+			if direction, ok := directionAggregates[body.Direction]; ok {
+				direction.Bandwidth += body.Bandwidth
+				direction.PacketsPerSecond += body.PacketsPerSecond
+			} else {
+				directionAggregates[body.Direction] = &payloads.RawBandwidthPayload{
+					Direction:        body.Direction,
+					Bandwidth:        body.Bandwidth,
+					PacketsPerSecond: body.PacketsPerSecond,
+				}
+			}
+		} else if body, ok := r.Body.(payloads.TargetRatePayload); ok {
+			targetTotal += body.Rate
+			targetSamples++
 		}
 	}
 
 	connectionBandwidths := make([]payloads.RawBandwidthPayload, 0, len(connectionAggregates))
 	for k, v := range connectionAggregates {
 		connectionBandwidths = append(connectionBandwidths, payloads.RawBandwidthPayload{
-			ConnectionID:     k,
+			ConnectionID:     k.id,
+			Direction:        k.direction,
 			Bandwidth:        1e9 * v.Bandwidth / nanos,
 			PacketsPerSecond: 1e9 * v.PacketsPerSecond / nanos,
+			TCPInfo:          v.TCPInfo,
 		})
 	}
 
 	// even with 100s of threads this should be relatively fast
 	sort.SliceStable(connectionBandwidths, func(i, j int) bool {
+		if connectionBandwidths[i].ConnectionID == connectionBandwidths[j].ConnectionID {
+			return connectionBandwidths[i].Direction < connectionBandwidths[j].Direction
+		}
 		return connectionBandwidths[i].ConnectionID < connectionBandwidths[j].ConnectionID
 	})
 
+	directionBandwidths := make([]payloads.RawBandwidthPayload, 0, len(directionAggregates))
+	for _, d := range []ethr.Direction{ethr.Upstream, ethr.Downstream} {
+		if v, ok := directionAggregates[d]; ok {
+			directionBandwidths = append(directionBandwidths, payloads.RawBandwidthPayload{
+				Direction:        d,
+				Bandwidth:        1e9 * v.Bandwidth / nanos,
+				PacketsPerSecond: 1e9 * v.PacketsPerSecond / nanos,
+			})
+		}
+	}
+
+	targetRate := uint64(0)
+	if targetSamples > 0 {
+		targetRate = targetTotal / targetSamples
+	}
+
 	return session.TestResult{
 		Success: true,
 		Error:   nil,
@@ -116,6 +330,8 @@ func BandwidthAggregator(nanos uint64, intermediateResults []session.TestResult)
 			TotalBandwidth:        1e9 * totalBandwidth / nanos,
 			TotalPacketsPerSecond: 1e9 * totalPackets / nanos,
 			ConnectionBandwidths:  connectionBandwidths,
+			DirectionBandwidths:   directionBandwidths,
+			TargetRate:            targetRate,
 		},
 	}
 }
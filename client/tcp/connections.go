@@ -38,11 +38,14 @@ func (t Tests) TestConnectionsPerSecond(test *session.Test) {
 }
 
 func ConnectionsAggregator(nanos uint64, intermediateResults []session.TestResult) session.TestResult {
-	connections := uint64(0)
+	connections, failed := uint64(0), uint64(0)
 	for _, r := range intermediateResults {
-		// ignore failed results
-		if body, ok := r.Body.(payloads.ConnectionsPerSecondPayload); ok && r.Success {
-			connections += body.Connections
+		if body, ok := r.Body.(payloads.ConnectionsPerSecondPayload); ok {
+			if r.Success {
+				connections += body.Connections
+			} else {
+				failed += body.Failed
+			}
 		}
 	}
 
@@ -51,6 +54,7 @@ func ConnectionsAggregator(nanos uint64, intermediateResults []session.TestResul
 		Error:   nil,
 		Body: payloads.ConnectionsPerSecondPayload{
 			Connections: 1e9 * connections / nanos,
+			Failed:      1e9 * failed / nanos,
 		},
 	}
 }
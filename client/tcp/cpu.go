@@ -0,0 +1,70 @@
+package tcp
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+	"weavelab.xyz/ethr/stats"
+)
+
+// senderTimeout bounds the wait for the senders to close once the test is done,
+// a send blocked on a stalled connection mustn't hold up the report.
+const senderTimeout = time.Second
+
+// cpuMeter counts the bytes the connections of a bandwidth test move, updated
+// atomically, to tell the CPU time the process spends per gigabit. senders is
+// the connections sending, sends is only complete once they are closed.
+type cpuMeter struct {
+	sent     uint64
+	received uint64
+	sends    ethr.SendStats
+	senders  sync.WaitGroup
+
+	mode        ethr.SendMode
+	startUser   time.Duration
+	startSystem time.Duration
+}
+
+func (m *cpuMeter) start() (err error) {
+	m.startUser, m.startSystem, err = stats.ProcessCPUTime()
+	return err
+}
+
+// reportCPUCost adds the CPU cost of the test to its summaries once it is done
+// and its senders are closed.
+func (t Tests) reportCPUCost(test *session.Test, meter *cpuMeter) {
+	defer test.Wrapup.Done()
+	<-test.Done
+	closed := make(chan struct{})
+	go func() {
+		meter.senders.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(senderTimeout):
+		t.Logger.Debug("Senders not closed after %s, reporting the CPU cost without them", senderTimeout)
+	}
+	user, system, err := stats.ProcessCPUTime()
+	if err != nil {
+		t.Logger.Debug("Failed to read CPU time: %v", err)
+		return
+	}
+	test.AddSummary(session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body: payloads.CPUCostPayload{
+			Mode:       meter.mode,
+			Sent:       atomic.LoadUint64(&meter.sent),
+			Received:   atomic.LoadUint64(&meter.received),
+			User:       user - meter.startUser,
+			System:     system - meter.startSystem,
+			ZeroCopied: atomic.LoadUint64(&meter.sends.ZeroCopied),
+			Copied:     atomic.LoadUint64(&meter.sends.Copied),
+		},
+	})
+}
@@ -0,0 +1,95 @@
+package tcp
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// integrityTimeout bounds the wait for the last send and for the server to tell
+// what it verified once the test is done.
+const integrityTimeout = 2 * time.Second
+
+// integrityMeter collects what was verified of the streams of a bandwidth test,
+// by the client of the streams it receives and by the server of the others.
+type integrityMeter struct {
+	// streams the server still has to report on
+	pending sync.WaitGroup
+
+	mu        sync.Mutex
+	verifiers map[string]*ethr.StreamVerifier
+	reported  []payloads.IntegrityPayload
+}
+
+func newIntegrityMeter() *integrityMeter {
+	return &integrityMeter{verifiers: make(map[string]*ethr.StreamVerifier)}
+}
+
+// verifier returns the verifier of the stream the client receives on connection id.
+func (m *integrityMeter) verifier(id string, seed int64) *ethr.StreamVerifier {
+	v := ethr.NewStreamVerifier(seed)
+	m.mu.Lock()
+	m.verifiers[id] = v
+	m.mu.Unlock()
+	return v
+}
+
+// receiveIntegrity stops sending on the connection and waits for the server to
+// tell what it verified of the stream.
+func (t Tests) receiveIntegrity(test *session.Test, conn net.Conn, id string, meter *integrityMeter) {
+	defer meter.pending.Done()
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		_ = tcpConn.CloseWrite()
+	}
+	_ = conn.SetReadDeadline(time.Now().Add(integrityTimeout))
+	msg, err := test.Session.Receive(conn)
+	if err != nil || msg.Type != ethr.Integrity || msg.Integrity == nil {
+		t.Logger.Error("The server didn't tell what it verified of connection %s: %v", id, err)
+		return
+	}
+	report := payloads.IntegrityPayload{
+		ConnectionID:    id,
+		Direction:       ethr.Upstream,
+		Verified:        msg.Integrity.Verified,
+		Corrupted:       msg.Integrity.Corrupted,
+		FirstCorruption: msg.Integrity.FirstCorruption,
+	}
+	meter.mu.Lock()
+	meter.reported = append(meter.reported, report)
+	meter.mu.Unlock()
+}
+
+// reportIntegrity adds what was verified of every stream to the summaries of
+// the test once it is done and the server told about its streams.
+func (t Tests) reportIntegrity(test *session.Test, meter *integrityMeter) {
+	defer test.Wrapup.Done()
+	<-test.Done
+	meter.pending.Wait()
+	meter.mu.Lock()
+	defer meter.mu.Unlock()
+	for _, r := range meter.reported {
+		test.AddSummary(session.TestResult{
+			Success: true,
+			Error:   nil,
+			Body:    r,
+		})
+	}
+	for id, v := range meter.verifiers {
+		test.AddSummary(session.TestResult{
+			Success: true,
+			Error:   nil,
+			Body: payloads.IntegrityPayload{
+				ConnectionID:    id,
+				Direction:       ethr.Downstream,
+				Verified:        atomic.LoadUint64(&v.Verified),
+				Corrupted:       atomic.LoadUint64(&v.Corrupted),
+				FirstCorruption: atomic.LoadUint64(&v.FirstCorruption),
+			},
+		})
+	}
+}
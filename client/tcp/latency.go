@@ -12,7 +12,7 @@ import (
 )
 
 func (t Tests) TestLatency(test *session.Test, g time.Duration) {
-	conn, err := t.NetTools.Dial(ethr.TCP, test.DialAddr, t.NetTools.LocalIP, t.NetTools.LocalPort, 0, 0)
+	conn, err := t.NetTools.DialStream(ethr.TCP, test.DialAddr, t.NetTools.LocalIP, t.NetTools.LocalPort, 0, 0, test.ClientParam.ForStream(0))
 	if err != nil {
 		test.Results <- session.TestResult{
 			Success: false,
@@ -22,7 +22,7 @@ func (t Tests) TestLatency(test *session.Test, g time.Duration) {
 		return
 	}
 	defer conn.Close()
-	err = test.Session.HandshakeWithServer(test, conn)
+	server, err := test.Session.HandshakeWithServerStream(test, 0, conn)
 	if err != nil {
 		test.Results <- session.TestResult{
 			Success: false,
@@ -31,14 +31,31 @@ func (t Tests) TestLatency(test *session.Test, g time.Duration) {
 		}
 		return
 	}
+	t.reportStreamOptions(test, conn, "0", server)
+
 	buffSize := test.ClientParam.BufferSize
 	buff := make([]byte, buffSize)
-	for i := uint32(0); i < buffSize; i++ {
-		buff[i] = byte(i)
+	filler, err := test.ClientParam.Content.NewFiller()
+	if err != nil {
+		test.Results <- session.TestResult{
+			Success: false,
+			Error:   err,
+			Body:    nil,
+		}
+		return
 	}
-	blen := len(buff)
+	filler.Fill(buff)
 	rttCount := test.ClientParam.RttCount
 	latencyNumbers := make([]time.Duration, rttCount)
+	// the server draws the size of each message it receives from the same sequence
+	params := test.ClientParam.ForStream(0)
+	sizer := ethr.NewSizer(params.Sizes, len(buff))
+	var sizes *sizeMeter
+	if params.Sizes.IsSet() {
+		sizes = newSizeMeter()
+		test.Wrapup.Add(1)
+		go t.reportSizes(test, sizes)
+	}
 	for {
 	ExitSelect:
 		select {
@@ -54,9 +71,13 @@ func (t Tests) TestLatency(test *session.Test, g time.Duration) {
 		default:
 			t0 := time.Now()
 			for i := uint32(0); i < rttCount; i++ {
+				size := sizer.Next()
+				if !filler.Static() {
+					filler.Fill(buff[:size])
+				}
 				s1 := time.Now()
-				n, err := conn.Write(buff)
-				if err != nil || n < blen {
+				n, err := conn.Write(buff[:size])
+				if err != nil || n < size {
 					test.AddDirectResult(session.TestResult{
 						Success: false,
 						Error:   fmt.Errorf("error sending/receiving data on connection: %w", err),
@@ -64,7 +85,7 @@ func (t Tests) TestLatency(test *session.Test, g time.Duration) {
 					})
 					break ExitSelect
 				}
-				_, err = io.ReadFull(conn, buff)
+				_, err = io.ReadFull(conn, buff[:size])
 				if err != nil {
 					test.AddDirectResult(session.TestResult{
 						Success: false,
@@ -75,14 +96,18 @@ func (t Tests) TestLatency(test *session.Test, g time.Duration) {
 				}
 				e2 := time.Since(s1)
 				latencyNumbers[i] = e2
+				sizes.roundTrip(size, e2)
 			}
 
-			_, _ = conn.Write(buff)
+			_, _ = conn.Write(buff[:sizer.Next()])
+			// nil on platforms without TCP_INFO
+			info, _ := t.NetTools.TCPInfo(conn)
 			test.AddIntermediateResult(session.TestResult{
 				Success: true,
 				Error:   nil,
 				Body: payloads.RawLatencies{
 					Latencies: latencyNumbers,
+					TCPInfo:   info,
 				},
 			})
 			t1 := time.Since(t0)
@@ -95,17 +120,23 @@ func (t Tests) TestLatency(test *session.Test, g time.Duration) {
 
 func LatencyAggregator(nanos uint64, intermediateResults []session.TestResult) session.TestResult {
 	latencies := make([]time.Duration, 0)
+	var info *payloads.TCPInfo
 
 	for _, r := range intermediateResults {
 		// ignore failed results
 		if body, ok := r.Body.(payloads.RawLatencies); ok && r.Success {
 			latencies = append(latencies, body.Latencies...)
+			if body.TCPInfo != nil {
+				info = body.TCPInfo
+			}
 		}
 	}
 
+	latency := payloads.NewLatencies(latencies)
+	latency.TCPInfo = info
 	return session.TestResult{
 		Success: true,
 		Error:   nil,
-		Body:    payloads.NewLatencies(latencies),
+		Body:    latency,
 	}
 }
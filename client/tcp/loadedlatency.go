@@ -0,0 +1,122 @@
+package tcp
+
+import (
+	"fmt"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// TestLatencyUnderLoad pings the server before, during and after a bandwidth
+// test saturating the path. The loaded phase takes half of the duration, the
+// idle and recovery phases a quarter each.
+func (t Tests) TestLatencyUnderLoad(test *session.Test, gap time.Duration, warmupCount uint32) {
+	// the samples of a phase are published until its collector is done, results
+	// must stay open until then even when the test is ended early
+	test.Wrapup.Add(1)
+	defer test.Wrapup.Done()
+	phase := test.ClientParam.Duration / 4
+	result := payloads.LoadedLatencyPayload{}
+	var ok bool
+	if result.Idle, _, ok = t.runLoadPhase(test, payloads.LoadPhaseIdle, phase, gap, warmupCount); !ok {
+		return
+	}
+	if result.Loaded, result.Bandwidth, ok = t.runLoadPhase(test, payloads.LoadPhaseLoaded, 2*phase, gap, 0); !ok {
+		return
+	}
+	if result.Recovery, _, ok = t.runLoadPhase(test, payloads.LoadPhaseRecovery, phase, gap, 0); !ok {
+		return
+	}
+
+	if result.Idle.Received == 0 {
+		test.AddDirectResult(session.TestResult{
+			Success: false,
+			Error:   fmt.Errorf("no connections to %s succeeded before the load, latency under load can't be measured", test.DialAddr),
+			Body:    nil,
+		})
+		test.Terminate()
+		return
+	}
+	if result.Loaded.Received == 0 {
+		// connections failing under load are as bad as it gets
+		result.Grade = "F"
+	} else {
+		if result.Loaded.Latency.Avg > result.Idle.Latency.Avg {
+			result.Increase = result.Loaded.Latency.Avg - result.Idle.Latency.Avg
+		}
+		result.Grade = payloads.BufferbloatGrade(result.Increase)
+	}
+	test.AddDirectResult(session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body:    result,
+	})
+	test.Terminate()
+}
+
+// runLoadPhase pings the server for the length of the phase, next to a
+// bandwidth test in the loaded phase. Pings are published once a second with
+// the bandwidth at that time, false is returned when the test was ended early.
+func (t Tests) runLoadPhase(test *session.Test, phase payloads.LoadPhase, d time.Duration, gap time.Duration, warmupCount uint32) (payloads.PingPayload, uint64, bool) {
+	ping := session.NewTest(test.Session, ethr.TCP, ethr.TestTypePing, test.RemoteIP, test.RemotePort, test.ClientParam, PingAggregator, time.Second)
+	go ping.StartPublishing()
+	t.TestPing(ping, gap, warmupCount)
+
+	var load *session.Test
+	if phase == payloads.LoadPhaseLoaded {
+		load = session.NewTest(test.Session, ethr.TCP, ethr.TestTypeBandwidth, test.RemoteIP, test.RemotePort, test.ClientParam, BandwidthAggregator, time.Second)
+		go load.StartPublishing()
+		t.TestBandwidth(load)
+	}
+
+	collected := make(chan payloads.PingPayload)
+	var bandwidth, intervals uint64
+	go func() {
+		total := payloads.PingPayload{}
+		latencies := make([]time.Duration, 0)
+		for r := range ping.Results {
+			body, ok := r.Body.(payloads.PingPayload)
+			if !ok {
+				continue
+			}
+			total.Sent += body.Sent
+			total.Lost += body.Lost
+			total.Received += body.Received
+			latencies = append(latencies, body.Latency.Raw...)
+
+			sample := payloads.LoadedLatencySample{Phase: phase, Ping: body}
+			if load != nil {
+				if bw, ok := load.LatestResult().Body.(payloads.BandwidthPayload); ok {
+					sample.Bandwidth = bw.TotalBandwidth
+					bandwidth += bw.TotalBandwidth
+					intervals++
+				}
+			}
+			test.AddDirectResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body:    sample,
+			})
+		}
+		total.Latency = payloads.NewLatencies(latencies)
+		collected <- total
+	}()
+
+	ended := false
+	select {
+	case <-time.After(d):
+	case <-test.Done:
+		ended = true
+	}
+	ping.Terminate()
+	if load != nil {
+		load.Terminate()
+	}
+	total := <-collected
+	if intervals > 0 {
+		bandwidth /= intervals
+	}
+	return total, bandwidth, !ended
+}
@@ -0,0 +1,323 @@
+package tcp
+
+import (
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// handshakeTimeout bounds the handshake of a new connection, a server out of
+// resources can accept connections in the kernel without ever answering.
+const handshakeTimeout = 5 * time.Second
+
+// connShard is the held connections one goroutine keeps active.
+type connShard struct {
+	mu     sync.Mutex
+	conns  []net.Conn
+	closed bool
+}
+
+// connHolder holds the connections of a max connections test and tracks the
+// time to connect at each level of held connections.
+type connHolder struct {
+	start   time.Time
+	open    int64
+	pending int64
+	shards  []*connShard
+
+	mu      sync.Mutex
+	peak    uint64
+	dropped uint64
+	summary payloads.MaxConnectionsSummaryPayload
+	// by the power of ten the number of held connections is up to
+	levels map[uint64][]time.Duration
+}
+
+func newConnHolder(shards int) *connHolder {
+	h := &connHolder{start: time.Now(), levels: make(map[uint64][]time.Duration)}
+	for i := 0; i < shards; i++ {
+		h.shards = append(h.shards, &connShard{})
+	}
+	return h
+}
+
+// add holds conn, false tells it was closed as the test is done.
+func (h *connHolder) add(conn net.Conn, latency time.Duration) bool {
+	open := uint64(atomic.AddInt64(&h.open, 1))
+	shard := h.shards[open%uint64(len(h.shards))]
+	shard.mu.Lock()
+	if shard.closed {
+		shard.mu.Unlock()
+		atomic.AddInt64(&h.open, -1)
+		_ = conn.Close()
+		return false
+	}
+	shard.conns = append(shard.conns, conn)
+	shard.mu.Unlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if open > h.peak {
+		h.peak = open
+	}
+	level := connectLevel(open)
+	h.levels[level] = append(h.levels[level], latency)
+	return true
+}
+
+func (h *connHolder) fail(failure ethr.ConnectFailure) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.summary.Failed() {
+		h.summary.FirstFailure = failure
+		h.summary.FirstFailureOpen = uint64(atomic.LoadInt64(&h.open))
+		h.summary.FirstFailureAfter = time.Since(h.start)
+	}
+	h.summary.Failures[failure]++
+}
+
+func (h *connHolder) drop() {
+	atomic.AddInt64(&h.open, -1)
+	h.mu.Lock()
+	h.dropped++
+	h.mu.Unlock()
+}
+
+// connectLevel returns the power of ten open is up to.
+func connectLevel(open uint64) uint64 {
+	level := uint64(10)
+	for level < open {
+		level *= 10
+	}
+	return level
+}
+
+// TestMaxConnections opens RampRate connections a second and holds them until
+// the test is done, to find how many the client, the server and the path in
+// between can hold at once. Failing connections don't stop the ramp.
+func (t Tests) TestMaxConnections(test *session.Test) {
+	holder := newConnHolder(int(test.ClientParam.NumThreads))
+	test.Wrapup.Add(1)
+	go t.reportMaxConnections(test, holder)
+	go publishOpenConnections(test, holder)
+	if test.ClientParam.KeepActive > 0 {
+		for _, shard := range holder.shards {
+			go t.keepActive(test, holder, shard)
+		}
+	}
+
+	rate := float64(test.ClientParam.RampRate)
+	max := int64(test.ClientParam.MaxConnections)
+	started := uint64(0)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-test.Done:
+			return
+		case <-ticker.C:
+		}
+		target := uint64(rate * time.Since(holder.start).Seconds())
+		for ; started < target; started++ {
+			if max > 0 && atomic.LoadInt64(&holder.open)+atomic.LoadInt64(&holder.pending) >= max {
+				// don't catch up on the attempts skipped at the limit once connections drop
+				started = target
+				break
+			}
+			atomic.AddInt64(&holder.pending, 1)
+			go t.openConnection(test, holder)
+		}
+	}
+}
+
+// openConnection opens a connection and hands it to the holder, the time to
+// connect doesn't include the handshake telling the server about the test.
+func (t Tests) openConnection(test *session.Test, holder *connHolder) {
+	defer atomic.AddInt64(&holder.pending, -1)
+	start := time.Now()
+	conn, err := t.NetTools.DialStream(ethr.TCP, test.DialAddr, t.NetTools.LocalIP, 0, 0, 0, test.ClientParam.ForStream(0))
+	latency := time.Since(start)
+	if err == nil {
+		_ = conn.SetDeadline(time.Now().Add(handshakeTimeout))
+		err = test.Session.HandshakeWithServer(test, conn)
+		if err != nil {
+			_ = conn.Close()
+		}
+	}
+	if err != nil {
+		failure := ethr.ClassifyConnectError(err)
+		t.Logger.Debug("Failed to open a connection (%s): %v", failure, err)
+		holder.fail(failure)
+		test.AddIntermediateResult(session.TestResult{
+			Success: true,
+			Error:   nil,
+			Body:    payloads.RawConnectPayload{Failed: true, Failure: failure},
+		})
+		return
+	}
+	_ = conn.SetDeadline(time.Time{})
+	if !holder.add(conn, latency) {
+		return
+	}
+	test.AddIntermediateResult(session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body:    payloads.RawConnectPayload{Latency: latency},
+	})
+}
+
+// keepActive exchanges a byte with the server on every connection of the shard
+// once per KeepActive, connections failing to are dropped. The bytes are all
+// sent before waiting for the answers, one slow connection doesn't hold up the
+// others.
+func (t Tests) keepActive(test *session.Test, holder *connHolder, shard *connShard) {
+	ticker := time.NewTicker(test.ClientParam.KeepActive)
+	defer ticker.Stop()
+	buff := make([]byte, 1)
+	for {
+		select {
+		case <-test.Done:
+			return
+		case <-ticker.C:
+		}
+		shard.mu.Lock()
+		conns := shard.conns
+		shard.mu.Unlock()
+		failed := make(map[net.Conn]error)
+		deadline := time.Now().Add(test.ClientParam.KeepActive)
+		for _, conn := range conns {
+			_ = conn.SetDeadline(deadline)
+			if _, err := conn.Write(buff); err != nil {
+				failed[conn] = err
+			}
+		}
+		for _, conn := range conns {
+			if _, ok := failed[conn]; ok {
+				continue
+			}
+			if _, err := io.ReadFull(conn, buff); err != nil {
+				failed[conn] = err
+			}
+		}
+		if len(failed) == 0 {
+			continue
+		}
+		select {
+		case <-test.Done:
+			// the connections were closed as the test is done
+			return
+		default:
+		}
+		shard.mu.Lock()
+		kept := make([]net.Conn, 0, len(shard.conns))
+		for _, conn := range shard.conns {
+			if _, ok := failed[conn]; !ok {
+				kept = append(kept, conn)
+			}
+		}
+		shard.conns = kept
+		shard.mu.Unlock()
+		for conn, err := range failed {
+			t.Logger.Debug("Dropped a held connection: %v", err)
+			_ = conn.Close()
+			holder.drop()
+			test.AddIntermediateResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body:    payloads.RawConnectPayload{Dropped: true},
+			})
+		}
+	}
+}
+
+// publishOpenConnections samples the connections held ten times a second, so
+// intervals without new connections know them too.
+func publishOpenConnections(test *session.Test, holder *connHolder) {
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-test.Done:
+			return
+		case <-ticker.C:
+			test.AddIntermediateResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body:    payloads.OpenConnectionsPayload{Open: uint64(atomic.LoadInt64(&holder.open))},
+			})
+		}
+	}
+}
+
+// reportMaxConnections closes the held connections once the test is done and
+// adds what it reached to its summaries.
+func (t Tests) reportMaxConnections(test *session.Test, holder *connHolder) {
+	defer test.Wrapup.Done()
+	<-test.Done
+	for _, shard := range holder.shards {
+		shard.mu.Lock()
+		for _, conn := range shard.conns {
+			_ = conn.Close()
+		}
+		shard.conns = nil
+		shard.closed = true
+		shard.mu.Unlock()
+	}
+
+	holder.mu.Lock()
+	defer holder.mu.Unlock()
+	summary := holder.summary
+	summary.Peak = holder.peak
+	summary.Dropped = holder.dropped
+	for level := uint64(10); len(summary.Levels) < len(holder.levels); level *= 10 {
+		if latencies, ok := holder.levels[level]; ok {
+			latency := payloads.NewLatencies(latencies)
+			// a million samples don't belong in the logs
+			latency.Raw = nil
+			summary.Levels = append(summary.Levels, payloads.ConnectLevelPayload{
+				UpTo:    level,
+				Opened:  uint64(len(latencies)),
+				Latency: latency,
+			})
+		}
+	}
+	test.AddSummary(session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body:    summary,
+	})
+}
+
+func MaxConnectionsAggregator(nanos uint64, intermediateResults []session.TestResult) session.TestResult {
+	result := payloads.MaxConnectionsPayload{}
+	latencies := make([]time.Duration, 0)
+	for _, r := range intermediateResults {
+		switch body := r.Body.(type) {
+		case payloads.RawConnectPayload:
+			if body.Dropped {
+				result.Dropped++
+			} else if body.Failed {
+				result.Failed++
+				result.Failures[body.Failure]++
+			} else {
+				result.Opened++
+				latencies = append(latencies, body.Latency)
+			}
+		case payloads.OpenConnectionsPayload:
+			result.Open = body.Open
+		}
+	}
+	result.Latency = payloads.NewLatencies(latencies)
+
+	return session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body:    result,
+	}
+}
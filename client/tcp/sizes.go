@@ -0,0 +1,87 @@
+package tcp
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// sizeCount is what was sent in messages of one size bucket, updated atomically.
+type sizeCount struct {
+	messages uint64
+	bytes    uint64
+	nanos    uint64
+}
+
+// sizeMeter breaks what the client sends out by the size bucket of each
+// message, for tests sending messages of varying sizes.
+type sizeMeter struct {
+	// indexed by the power of two of the bucket
+	counts [32]sizeCount
+
+	mu        sync.Mutex
+	latencies map[uint32][]time.Duration
+}
+
+func newSizeMeter() *sizeMeter {
+	return &sizeMeter{latencies: make(map[uint32][]time.Duration)}
+}
+
+func (m *sizeMeter) sent(size int, took time.Duration) {
+	if m == nil {
+		return
+	}
+	c := &m.counts[bits.Len32(ethr.SizeBucket(size))-1]
+	atomic.AddUint64(&c.messages, 1)
+	atomic.AddUint64(&c.bytes, uint64(size))
+	atomic.AddUint64(&c.nanos, uint64(took))
+}
+
+func (m *sizeMeter) roundTrip(size int, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	m.sent(size, 0)
+	m.mu.Lock()
+	bucket := ethr.SizeBucket(size)
+	m.latencies[bucket] = append(m.latencies[bucket], latency)
+	m.mu.Unlock()
+}
+
+// reportSizes adds the results by message size to the summaries of the test
+// once it is done.
+func (t Tests) reportSizes(test *session.Test, meter *sizeMeter) {
+	defer test.Wrapup.Done()
+	<-test.Done
+	buckets := make([]payloads.SizeBucketPayload, 0)
+	meter.mu.Lock()
+	defer meter.mu.Unlock()
+	for i := range meter.counts {
+		c := &meter.counts[i]
+		messages := atomic.LoadUint64(&c.messages)
+		if messages == 0 {
+			continue
+		}
+		bucket := payloads.SizeBucketPayload{
+			UpTo:     uint32(1) << uint(i),
+			Messages: messages,
+			Bytes:    atomic.LoadUint64(&c.bytes),
+			SendTime: time.Duration(atomic.LoadUint64(&c.nanos)),
+		}
+		if latencies, ok := meter.latencies[bucket.UpTo]; ok {
+			latency := payloads.NewLatencies(latencies)
+			bucket.Latency = &latency
+		}
+		buckets = append(buckets, bucket)
+	}
+	test.AddSummary(session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body:    payloads.SizeBucketsPayload{Buckets: buckets},
+	})
+}
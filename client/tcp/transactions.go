@@ -0,0 +1,163 @@
+package tcp
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// TestRequestResponse runs request/response transactions on NumThreads
+// connections at once, like netperf's TCP_RR. With connect every transaction
+// opens its own connection and closes it once the response is in, like TCP_CRR.
+func (t Tests) TestRequestResponse(test *session.Test, connect bool) {
+	for th := uint32(0); th < test.ClientParam.NumThreads; th++ {
+		if connect {
+			go t.connectRequestResponse(test, int(th))
+			continue
+		}
+		conn, err := t.dialTransactions(test, int(th))
+		if err != nil {
+			t.Logger.Error("Failed to open connection %d: %v", th, err)
+			continue
+		}
+		go func() {
+			defer conn.Close()
+			request, response := transactionBuffers(test)
+			for {
+				select {
+				case <-test.Done:
+					return
+				default:
+				}
+				latency, err := transaction(conn, request, response)
+				if err != nil {
+					t.Logger.Debug("Stopped transactions on a connection: %v", err)
+					return
+				}
+				addTransaction(test, latency)
+			}
+		}()
+	}
+}
+
+// connectRequestResponse opens a connection per transaction until the test is
+// done. The latency of a transaction is the time to connect and the round trip
+// of the request, the handshake telling the server about the test isn't part of it.
+// Failed transactions are counted and retried after a backoff.
+func (t Tests) connectRequestResponse(test *session.Test, th int) {
+	request, response := transactionBuffers(test)
+	var backoff ethr.Backoff
+	for {
+		select {
+		case <-test.Done:
+			return
+		default:
+		}
+		latency, err := t.connectTransaction(test, th, request, response)
+		if err != nil {
+			t.Logger.Debug("Failed a transaction: %v", err)
+			test.AddIntermediateResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body:    payloads.RawTransaction{Failed: true},
+			})
+			backoff.Wait(test.Done)
+			continue
+		}
+		backoff.Reset()
+		addTransaction(test, latency)
+	}
+}
+
+// connectTransaction runs one transaction on a connection of its own.
+func (t Tests) connectTransaction(test *session.Test, th int, request []byte, response []byte) (time.Duration, error) {
+	start := time.Now()
+	conn, err := t.NetTools.DialStream(ethr.TCP, test.DialAddr, t.NetTools.LocalIP, 0, 0, 0, test.ClientParam.ForStream(th))
+	if err != nil {
+		return 0, fmt.Errorf("failed to open a connection: %w", err)
+	}
+	defer conn.Close()
+	connected := time.Since(start)
+	_, err = test.Session.HandshakeWithServerStream(test, th, conn)
+	if err != nil {
+		return 0, fmt.Errorf("failed in handshake with the server: %w", err)
+	}
+	latency, err := transaction(conn, request, response)
+	if err != nil {
+		return 0, err
+	}
+	return connected + latency, nil
+}
+
+func (t Tests) dialTransactions(test *session.Test, th int) (net.Conn, error) {
+	conn, err := t.NetTools.DialStream(ethr.TCP, test.DialAddr, t.NetTools.LocalIP, 0, 0, 0, test.ClientParam.ForStream(th))
+	if err != nil {
+		return nil, err
+	}
+	_, err = test.Session.HandshakeWithServerStream(test, th, conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed in handshake with the server: %w", err)
+	}
+	return conn, nil
+}
+
+func transactionBuffers(test *session.Test) ([]byte, []byte) {
+	request := make([]byte, test.ClientParam.BufferSize)
+	for i := range request {
+		request[i] = byte(i)
+	}
+	return request, make([]byte, test.ClientParam.ResponseSize)
+}
+
+// transaction sends a request and waits for the whole response.
+func transaction(conn net.Conn, request []byte, response []byte) (time.Duration, error) {
+	start := time.Now()
+	_, err := conn.Write(request)
+	if err != nil {
+		return 0, fmt.Errorf("error sending request: %w", err)
+	}
+	_, err = io.ReadFull(conn, response)
+	if err != nil {
+		return 0, fmt.Errorf("error receiving response: %w", err)
+	}
+	return time.Since(start), nil
+}
+
+func addTransaction(test *session.Test, latency time.Duration) {
+	test.AddIntermediateResult(session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body:    payloads.RawTransaction{Latency: latency},
+	})
+}
+
+func TransactionsAggregator(nanos uint64, intermediateResults []session.TestResult) session.TestResult {
+	latencies := make([]time.Duration, 0, len(intermediateResults))
+	failed := uint64(0)
+	for _, r := range intermediateResults {
+		// ignore failed results
+		if body, ok := r.Body.(payloads.RawTransaction); ok && r.Success {
+			if body.Failed {
+				failed++
+			} else {
+				latencies = append(latencies, body.Latency)
+			}
+		}
+	}
+
+	return session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body: payloads.TransactionsPayload{
+			TransactionsPerSecond: 1e9 * uint64(len(latencies)) / nanos,
+			FailedPerSecond:       1e9 * failed / nanos,
+			Latency:               payloads.NewLatencies(latencies),
+		},
+	}
+}
@@ -11,6 +11,12 @@ import (
 )
 
 func (t Tools) Dial(p ethr.Protocol, dialAddr string, localIP net.IP, localPort uint16, ttl int, tos int) (net.Conn, error) {
+	return t.DialStream(p, dialAddr, localIP, localPort, ttl, tos, ethr.ClientParams{})
+}
+
+// DialStream dials a stream of a test, the TCP socket options requested in
+// params are set before connecting.
+func (t Tools) DialStream(p ethr.Protocol, dialAddr string, localIP net.IP, localPort uint16, ttl int, tos int, params ethr.ClientParams) (net.Conn, error) {
 	var lAddr net.Addr
 	var network string
 	var err error
@@ -39,10 +45,18 @@ func (t Tools) Dial(p ethr.Protocol, dialAddr string, localIP net.IP, localPort
 		LocalAddr: lAddr,
 		Timeout:   time.Second,
 		Control: func(network, address string, rc syscall.RawConn) error {
-			return rc.Control(func(fd uintptr) {
+			var optErr error
+			err := rc.Control(func(fd uintptr) {
 				_ = t.setTTL(fd, ttl, t.IPVersion)
 				_ = t.setTOS(fd, tos, t.IPVersion)
+				if p == ethr.TCP {
+					optErr = ethr.SetStreamOptions(fd, params)
+				}
 			})
+			if err != nil {
+				return err
+			}
+			return optErr
 		},
 	}
 	conn, err := dialer.Dial(network, dialAddr)
@@ -51,7 +65,13 @@ func (t Tools) Dial(p ethr.Protocol, dialAddr string, localIP net.IP, localPort
 	}
 	tcpConn, ok := conn.(*net.TCPConn)
 	if ok {
-		_ = tcpConn.SetLinger(0)
+		// Go disables Nagle's algorithm once connected
+		if params.Nagle {
+			_ = tcpConn.SetNoDelay(false)
+		}
+		if !params.SetLinger {
+			_ = tcpConn.SetLinger(0)
+		}
 		return tcpConn, nil
 	}
 	udpConn, ok := conn.(*net.UDPConn)
@@ -85,3 +105,14 @@ func (t Tools) setTOS(fd uintptr, tos int, ipVersion ethr.IPVersion) error {
 	}
 	return t.setTClass(fd, tos)
 }
+
+// StreamOptions reads back the socket options the kernel granted a TCP stream.
+func (t Tools) StreamOptions(conn net.Conn) (ethr.StreamOptions, error) {
+	var options ethr.StreamOptions
+	err := ethr.ControlSocket(conn, func(fd uintptr) error {
+		var err error
+		options, err = ethr.GetStreamOptions(fd)
+		return err
+	})
+	return options, err
+}
@@ -108,3 +108,39 @@ func (t Tools) UnwrapICMPMessage(index int, body []byte) (*icmp.Message, error)
 	}
 	return unwrapped, nil
 }
+
+// SetDontFragment makes the ICMP packets sent on pc keep their size on the path,
+// routers that can't forward them answer with Fragmentation Needed or Packet Too
+// Big instead.
+func (t Tools) SetDontFragment(pc net.PacketConn) error {
+	return ethr.ControlSocket(pc, func(fd uintptr) error {
+		return t.setDontFragment(fd, t.IPVersion)
+	})
+}
+
+// InterfaceMTU returns the MTU of the interface packets to the remote leave on.
+func (t Tools) InterfaceMTU(remote net.IP) (int, error) {
+	conn, err := net.Dial(ethr.UDPVersion(t.IPVersion), (&net.UDPAddr{IP: remote, Port: 9}).String())
+	if err != nil {
+		return 0, fmt.Errorf("failed to find route to %s: %w", remote, err)
+	}
+	local := conn.LocalAddr().(*net.UDPAddr).IP
+	_ = conn.Close()
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return 0, fmt.Errorf("failed to list interfaces: %w", err)
+	}
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(local) {
+				return iface.MTU, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no interface found for %s: %w", local, os.ErrNotExist)
+}
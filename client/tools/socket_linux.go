@@ -7,8 +7,11 @@ import (
 	"net"
 	"os"
 	"syscall"
+	"time"
+	"unsafe"
 
 	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session/payloads"
 )
 
 func (t Tools) setSockOptInt(fd uintptr, level, opt, val int) error {
@@ -40,3 +43,50 @@ func (t Tools) IcmpNewConn(address string) (net.PacketConn, error) {
 	}
 	return conn, nil
 }
+
+// setDontFragment sets the DF bit without using the path MTU cached by the
+// kernel, probes bigger than the cached MTU are still sent.
+func (t Tools) setDontFragment(fd uintptr, ipVersion ethr.IPVersion) error {
+	if ipVersion == ethr.IPv6 {
+		return t.setSockOptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_MTU_DISCOVER, syscall.IPV6_PMTUDISC_PROBE)
+	}
+	return t.setSockOptInt(fd, syscall.IPPROTO_IP, syscall.IP_MTU_DISCOVER, syscall.IP_PMTUDISC_PROBE)
+}
+
+// tcpInfo is struct tcp_info up to tcpi_rcv_wnd, syscall only has the fields
+// up to tcpi_total_retrans. Older kernels fill fewer fields and leave the rest 0.
+type tcpInfo struct {
+	state, caState, retransmits, probes, backoff, options, wscale, flags uint8
+
+	rto, ato, sndMss, rcvMss                             uint32
+	unacked, sacked, lost, retrans, fackets              uint32
+	lastDataSent, lastAckSent, lastDataRecv, lastAckRecv uint32
+	pmtu, rcvSsthresh, rtt, rttvar, sndSsthresh, sndCwnd uint32
+	advmss, reordering, rcvRtt, rcvSpace, totalRetrans   uint32
+	pacingRate, maxPacingRate, bytesAcked, bytesReceived uint64
+	segsOut, segsIn, notsentBytes, minRtt, dataSegsIn    uint32
+	dataSegsOut                                          uint32
+	deliveryRate, busyTime, rwndLimited, sndbufLimited   uint64
+	delivered, deliveredCe                               uint32
+	bytesSent, bytesRetrans                              uint64
+	dsackDups, reordSeen, rcvOoopack, sndWnd, rcvWnd     uint32
+}
+
+func (t Tools) getTCPInfo(fd uintptr) (*payloads.TCPInfo, error) {
+	var info tcpInfo
+	size := uint32(unsafe.Sizeof(info))
+	_, _, errno := syscall.Syscall6(syscall.SYS_GETSOCKOPT, fd, syscall.IPPROTO_TCP, syscall.TCP_INFO,
+		uintptr(unsafe.Pointer(&info)), uintptr(unsafe.Pointer(&size)), 0)
+	if errno != 0 {
+		return nil, fmt.Errorf("failed to get TCP_INFO: %w", errno)
+	}
+	return &payloads.TCPInfo{
+		CongestionWindow: uint64(info.sndCwnd) * uint64(info.sndMss),
+		SmoothedRTT:      time.Duration(info.rtt) * time.Microsecond,
+		Retransmits:      info.totalRetrans,
+		DeliveryRate:     info.deliveryRate,
+		PacingRate:       info.pacingRate,
+		SendWindow:       info.sndWnd,
+		ReceiveWindow:    info.rcvWnd,
+	}, nil
+}
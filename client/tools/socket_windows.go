@@ -11,6 +11,7 @@ import (
 	"unsafe"
 
 	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session/payloads"
 )
 
 func (t Tools) setSockOptInt(fd uintptr, level, opt, val int) error {
@@ -83,3 +84,20 @@ func (t Tools) IcmpNewConn(address string) (net.PacketConn, error) {
 
 	return conn, nil
 }
+
+// not exported by the syscall package for windows
+const (
+	ipDontFragment = 14
+	ipv6DontFrag   = 14
+)
+
+func (t Tools) setDontFragment(fd uintptr, ipVersion ethr.IPVersion) error {
+	if ipVersion == ethr.IPv6 {
+		return t.setSockOptInt(fd, syscall.IPPROTO_IPV6, ipv6DontFrag, 1)
+	}
+	return t.setSockOptInt(fd, syscall.IPPROTO_IP, ipDontFragment, 1)
+}
+
+func (t Tools) getTCPInfo(fd uintptr) (*payloads.TCPInfo, error) {
+	return nil, fmt.Errorf("failed to get TCP_INFO: %w", ethr.ErrNotSupported)
+}
@@ -0,0 +1,22 @@
+package tools
+
+import (
+	"net"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// TCPInfo samples the kernel's congestion control and window state of conn.
+func (t Tools) TCPInfo(conn net.Conn) (*payloads.TCPInfo, error) {
+	var info *payloads.TCPInfo
+	err := ethr.ControlSocket(conn, func(fd uintptr) error {
+		var err error
+		info, err = t.getTCPInfo(fd)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return info, nil
+}
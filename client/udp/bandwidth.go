@@ -4,6 +4,7 @@ import (
 	"net"
 	"sort"
 	"strconv"
+	"time"
 
 	"weavelab.xyz/ethr/ethr"
 	"weavelab.xyz/ethr/session"
@@ -12,59 +13,317 @@ import (
 )
 
 func (t Tests) TestBandwidth(test *session.Test) {
+	var shared *stats.Limiter
+	if test.ClientParam.SharedRate {
+		shared = stats.NewLimiter(test.ClientParam)
+	}
 	for th := uint32(0); th < test.ClientParam.NumThreads; th++ {
+		test.Wrapup.Add(1)
 		go func(th uint32) {
 			conn, err := t.NetTools.Dial(ethr.UDP, test.DialAddr, t.NetTools.LocalIP, t.NetTools.LocalPort+uint16(th), 0, 0)
 			if err != nil {
+				test.Wrapup.Done()
 				return
 			}
-			go t.handleBandwidthConn(test, conn, strconv.Itoa(int(th)))
+			go t.handleBandwidthConn(test, conn, int(th), shared)
 		}(th)
 	}
 }
 
-func (t Tests) handleBandwidthConn(test *session.Test, conn net.Conn, id string) {
+// handleBandwidthConn numbers the datagrams stream th sends when the buffer can
+// hold a datagram header, the server reports loss for those when the test is done.
+// In reverse and bidirectional tests the server streams to this connection as
+// well. shared limits the streams of tests sharing their rate.
+func (t Tests) handleBandwidthConn(test *session.Test, conn net.Conn, th int, shared *stats.Limiter) {
+	defer test.Wrapup.Done()
 	defer conn.Close()
 
-	buffer := make([]byte, test.ClientParam.BufferSize)
-	totalBytesToSend := test.ClientParam.BwRate
-	sentBytes := uint64(0)
-	start, waitTime, bytesToSend := stats.BeginThrottle(totalBytesToSend, len(buffer))
+	id := strconv.Itoa(th)
+	params := test.ClientParam.ForStream(th)
+
+	sending := !test.ClientParam.Reverse
+	receiving := test.ClientParam.Reverse || test.ClientParam.Bidirectional
+	r := &streamReceiver{
+		tracker:  stats.NewStreamTracker(),
+		reports:  make(chan ethr.StreamReport, 1),
+		finished: make(chan uint64, 1),
+		cookies:  make(chan uint64, 1),
+		stop:     make(chan struct{}),
+	}
+	defer close(r.stop)
+	if test.ClientParam.GSO && receiving {
+		// the server sends its stream segmented as well
+		if err := ethr.EnableUDPGRO(conn); err != nil {
+			t.Logger.Debug("Failed to enable UDP GRO on connection %s: %v", id, err)
+		}
+	}
+	go t.receiveStream(test, conn, id, r)
+	if udpConn, ok := conn.(*net.UDPConn); ok && receiving {
+		// the server sends in bursts when unlimited or given a large -burst
+		_ = udpConn.SetReadBuffer(receiveBufferSize)
+	}
+
+	startDatagram := make([]byte, ethr.StreamStartLen)
+	start := ethr.StreamStart{
+		BufferSize: params.BufferSize,
+		BwRate:     params.BwRate,
+		BatchSize:  params.BatchSize,
+		GSO:        params.GSO,
+		Pacing:     params.Pacing,
+		Burst:      params.Burst,
+		Content:    params.Content.Mode,
+	}
+	start.Encode(startDatagram)
+	lastStart := time.Time{}
+
+	batchSize := connBatchSize(test, conn)
+	batch := newDatagramBatch(conn, batchSize, int(test.ClientParam.BufferSize), test.ClientParam.GSO)
+	buffer := batch.buffers[0]
+	numbered := len(buffer) >= ethr.DatagramHeaderLen
+	// the headers of numbered datagrams are left out of the content
+	contentStart := 0
+	if numbered {
+		contentStart = ethr.DatagramHeaderLen
+	}
+	filler, err := params.Content.NewFiller()
+	if err != nil {
+		t.Logger.Error("Failed to fill the datagrams of connection %s: %v", id, err)
+		return
+	}
+	for _, b := range batch.buffers {
+		filler.Fill(b[contentStart:])
+	}
+	sequence := uint64(0)
+	var limiter *stats.Limiter
+	if sending {
+		limiter, err = stats.StreamLimiter(params, conn, shared)
+		if err != nil {
+			t.Logger.Error("Failed to pace connection %s in the kernel, pacing it in user space: %v", id, err)
+		}
+	}
+	bytesToSend := minDatagramSize(numbered, limiter.LimitDatagram(len(buffer)))
 	for {
+		select {
+		case start.Cookie = <-r.cookies:
+			// the server streams once its cookie is echoed
+			start.Encode(startDatagram)
+			lastStart = time.Time{}
+		default:
+		}
+		// the start datagram is repeated in case it is lost and to keep the server sending
+		if receiving && time.Since(lastStart) >= keepaliveInterval {
+			_, _ = conn.Write(startDatagram)
+			lastStart = time.Now()
+		}
+
 		select {
 		case <-test.Done:
+			t.finishStream(test, conn, id, sequence, sending && numbered, receiving, r)
 			return
 		default:
-			n, err := conn.Write(buffer[:bytesToSend])
-			if err != nil {
+			if !sending {
+				time.Sleep(keepaliveInterval / 10)
+				continue
+			}
+			count := limiter.LimitBatch(batchSize, bytesToSend)
+			if !limiter.Wait(test.Done, count*bytesToSend) {
 				continue
 			}
-			if n < bytesToSend {
+			if !filler.Static() {
+				for i := 0; i < count; i++ {
+					filler.Fill(batch.buffers[i][contentStart:bytesToSend])
+				}
+			}
+			if numbered {
+				now := time.Now().UnixNano()
+				for i := 0; i < count; i++ {
+					ethr.DatagramHeader{Type: ethr.DatagramData, Sequence: sequence + uint64(i) + 1, SendTime: now}.Encode(batch.buffers[i])
+				}
+			}
+			sent, err := batch.send(count, bytesToSend)
+			if err != nil {
+				t.Logger.Error("UDP GSO failed on connection %s, sending datagrams one at a time: %v", id, err)
+			}
+			if sent == 0 {
 				continue
 			}
+			if numbered {
+				sequence += uint64(sent)
+			}
 
+			n := sent * bytesToSend
 			test.AddIntermediateResult(session.TestResult{
 				Success: true,
 				Error:   nil,
 				Body: payloads.RawBandwidthPayload{
 					ConnectionID:     id,
+					Direction:        ethr.Upstream,
 					Bandwidth:        uint64(n),
-					PacketsPerSecond: 1,
+					PacketsPerSecond: uint64(sent),
 				},
 			})
 
-			if !test.ClientParam.Reverse {
-				sentBytes += uint64(n)
-				start, waitTime, sentBytes, bytesToSend = stats.EnforceThrottle(start, waitTime, totalBytesToSend, sentBytes, len(buffer))
+		}
+	}
+}
+
+// minDatagramSize keeps limited datagrams from cutting off their header.
+func minDatagramSize(numbered bool, bytesToSend int) int {
+	if numbered && bytesToSend < ethr.DatagramHeaderLen {
+		return ethr.DatagramHeaderLen
+	}
+	return bytesToSend
+}
+
+// streamReceiver collects what the server sends to a connection: the stream of
+// reverse and bidirectional tests and the answers to DatagramStart and DatagramDone.
+type streamReceiver struct {
+	tracker  *stats.StreamTracker
+	reports  chan ethr.StreamReport
+	finished chan uint64 // number of datagrams sent by the server
+	cookies  chan uint64
+	stop     chan struct{}
+}
+
+func (t Tests) receiveStream(test *session.Test, conn net.Conn, id string, r *streamReceiver) {
+	count := 1
+	if !test.ClientParam.GSO {
+		count = connBatchSize(test, conn)
+	}
+	batch := newDatagramBatch(conn, count, 64*1024, test.ClientParam.GSO)
+	for {
+		datagrams, err := batch.receive()
+		arrival := time.Now()
+		if err != nil {
+			select {
+			case <-r.stop:
+				return
+			default:
+				// connection refused is reported until the server is up, keep reading
+				continue
 			}
 		}
+
+		received, bytesReceived := 0, 0
+		for _, b := range datagrams {
+			header, ok := ethr.DecodeDatagramHeader(b)
+			if !ok {
+				continue
+			}
+			switch header.Type {
+			case ethr.DatagramData:
+				r.tracker.Add(header.Sequence, header.SendTime, arrival)
+				received++
+				bytesReceived += len(b)
+			case ethr.DatagramReport:
+				if report, ok := ethr.DecodeStreamReport(b); ok {
+					select {
+					case r.reports <- report:
+					default:
+					}
+				}
+			case ethr.DatagramDone:
+				select {
+				case r.finished <- header.Sequence:
+				default:
+				}
+			case ethr.DatagramCookie:
+				select {
+				case r.cookies <- header.Sequence:
+				default:
+				}
+			}
+		}
+		if received > 0 {
+			test.AddIntermediateResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body: payloads.RawBandwidthPayload{
+					ConnectionID:     id,
+					Direction:        ethr.Downstream,
+					Bandwidth:        uint64(bytesReceived),
+					PacketsPerSecond: uint64(received),
+				},
+			})
+		}
 	}
 }
 
+// finishStream tells the server the test is done, retrying a few times as either
+// datagram may be lost. The server answers with the report of the upstream and,
+// when it was sending, the number of datagrams it sent downstream.
+func (t Tests) finishStream(test *session.Test, conn net.Conn, id string, sent uint64, wantReport, receiving bool, r *streamReceiver) {
+	done := make([]byte, ethr.DatagramHeaderLen)
+	ethr.DatagramHeader{Type: ethr.DatagramDone, Sequence: sent}.Encode(done)
+
+	var report *ethr.StreamReport
+	serverSent, finished := uint64(0), false
+	for attempt := 0; attempt < reportAttempts && ((wantReport && report == nil) || (receiving && !finished)); attempt++ {
+		_, err := conn.Write(done)
+		if err != nil {
+			break
+		}
+		timeout := time.NewTimer(reportTimeout)
+	Wait:
+		for (wantReport && report == nil) || (receiving && !finished) {
+			select {
+			case rep := <-r.reports:
+				if rep.Sent == sent {
+					report = &rep
+				}
+			case serverSent = <-r.finished:
+				finished = true
+			case <-timeout.C:
+				break Wait
+			}
+		}
+		timeout.Stop()
+	}
+
+	if wantReport {
+		if report != nil {
+			test.AddSummary(streamSummary(id, ethr.Upstream, *report))
+		} else {
+			t.Logger.Info("No stream report received from server for connection %s", id)
+		}
+	}
+	if receiving {
+		if !finished {
+			// without the count of the server, datagrams lost at the end are not noticed
+			t.Logger.Info("No final count received from server for connection %s", id)
+		}
+		test.AddSummary(streamSummary(id, ethr.Downstream, r.tracker.Report(serverSent)))
+	}
+}
+
+func streamSummary(id string, direction ethr.Direction, report ethr.StreamReport) session.TestResult {
+	return session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body: payloads.DatagramStatsPayload{
+			ConnectionID: id,
+			Direction:    direction,
+			Sent:         report.Sent,
+			Received:     report.Received,
+			Lost:         report.Lost,
+			OutOfOrder:   report.OutOfOrder,
+			Duplicates:   report.Duplicates,
+			Jitter:       time.Duration(report.Jitter),
+		},
+	}
+}
+
+type connectionKey struct {
+	id        string
+	direction ethr.Direction
+}
+
 func BandwidthAggregator(nanos uint64, intermediateResults []session.TestResult) session.TestResult {
 	totalBandwidth := uint64(0)
 	totalPackets := uint64(0)
-	connectionAggregates := make(map[string]*payloads.RawBandwidthPayload)
+	targetTotal, targetSamples := uint64(0), uint64(0)
+	connectionAggregates := make(map[connectionKey]*payloads.RawBandwidthPayload)
+	directionAggregates := make(map[ethr.Direction]*payloads.RawBandwidthPayload)
 
 	for _, r := range intermediateResults {
 		// ignore failed results
@@ -72,19 +331,35 @@ func BandwidthAggregator(nanos uint64, intermediateResults []session.TestResult)
 			totalBandwidth += body.Bandwidth
 			totalPackets += body.PacketsPerSecond
 
-			if connection, ok := connectionAggregates[body.ConnectionID]; ok {
+			key := connectionKey{body.ConnectionID, body.Direction}
+			if connection, ok := connectionAggregates[key]; ok {
 				connection.Bandwidth += body.Bandwidth
 				connection.PacketsPerSecond += body.PacketsPerSecond
 			} else {
-				connectionAggregates[body.ConnectionID] = &body
+				b := body
+				connectionAggregates[key] = &b
+			}
+			if direction, ok := directionAggregates[body.Direction]; ok {
+				direction.Bandwidth += body.Bandwidth
+				direction.PacketsPerSecond += body.PacketsPerSecond
+			} else {
+				directionAggregates[body.Direction] = &payloads.RawBandwidthPayload{
+					Direction:        body.Direction,
+					Bandwidth:        body.Bandwidth,
+					PacketsPerSecond: body.PacketsPerSecond,
+				}
 			}
+		} else if body, ok := r.Body.(payloads.TargetRatePayload); ok {
+			targetTotal += body.Rate
+			targetSamples++
 		}
 	}
 
 	connectionBandwidths := make([]payloads.RawBandwidthPayload, 0, len(connectionAggregates))
 	for k, v := range connectionAggregates {
 		connectionBandwidths = append(connectionBandwidths, payloads.RawBandwidthPayload{
-			ConnectionID:     k,
+			ConnectionID:     k.id,
+			Direction:        k.direction,
 			Bandwidth:        1e9 * v.Bandwidth / nanos,
 			PacketsPerSecond: 1e9 * v.PacketsPerSecond / nanos,
 		})
@@ -92,9 +367,28 @@ func BandwidthAggregator(nanos uint64, intermediateResults []session.TestResult)
 
 	// even with 100s of threads this should be relatively fast
 	sort.SliceStable(connectionBandwidths, func(i, j int) bool {
+		if connectionBandwidths[i].ConnectionID == connectionBandwidths[j].ConnectionID {
+			return connectionBandwidths[i].Direction < connectionBandwidths[j].Direction
+		}
 		return connectionBandwidths[i].ConnectionID < connectionBandwidths[j].ConnectionID
 	})
 
+	directionBandwidths := make([]payloads.RawBandwidthPayload, 0, len(directionAggregates))
+	for _, d := range []ethr.Direction{ethr.Upstream, ethr.Downstream} {
+		if v, ok := directionAggregates[d]; ok {
+			directionBandwidths = append(directionBandwidths, payloads.RawBandwidthPayload{
+				Direction:        d,
+				Bandwidth:        1e9 * v.Bandwidth / nanos,
+				PacketsPerSecond: 1e9 * v.PacketsPerSecond / nanos,
+			})
+		}
+	}
+
+	targetRate := uint64(0)
+	if targetSamples > 0 {
+		targetRate = targetTotal / targetSamples
+	}
+
 	return session.TestResult{
 		Success: true,
 		Error:   nil,
@@ -102,6 +396,8 @@ func BandwidthAggregator(nanos uint64, intermediateResults []session.TestResult)
 			TotalBandwidth:        1e9 * totalBandwidth / nanos,
 			TotalPacketsPerSecond: 1e9 * totalPackets / nanos,
 			ConnectionBandwidths:  connectionBandwidths,
+			DirectionBandwidths:   directionBandwidths,
+			TargetRate:            targetRate,
 		},
 	}
 }
@@ -0,0 +1,133 @@
+package udp
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+)
+
+// connBatchSize is the number of datagrams the connection sends per system call,
+// only UDP sockets can batch. With GSO it is the number of segments that fit a
+// single send.
+func connBatchSize(test *session.Test, conn net.Conn) int {
+	if _, ok := conn.(net.PacketConn); !ok {
+		return 1
+	}
+	if test.ClientParam.GSO {
+		segments := ethr.MaxSegmentedBytes / int(test.ClientParam.BufferSize)
+		if segments > ethr.UDPMaxSegments {
+			segments = ethr.UDPMaxSegments
+		}
+		if segments < 1 {
+			return 1
+		}
+		return segments
+	}
+	if test.ClientParam.BatchSize < 1 {
+		return 1
+	}
+	return int(test.ClientParam.BatchSize)
+}
+
+// datagramBatch sends and receives datagrams one at a time with Read and Write,
+// in batches with sendmmsg and recvmmsg or, when segmenting, as a single buffer
+// split and coalesced by the kernel with UDP GSO and GRO.
+type datagramBatch struct {
+	conn      net.Conn
+	batch     ethr.BatchConn
+	messages  []ipv4.Message
+	buffers   [][]byte
+	datagrams [][]byte
+
+	// with GSO and GRO the buffers are consecutive slices of segmented
+	udpConn    *net.UDPConn
+	segmented  []byte
+	size       int
+	segmentOOB []byte
+	segmentErr error
+	oob        []byte
+}
+
+func newDatagramBatch(conn net.Conn, count, size int, segment bool) *datagramBatch {
+	b := &datagramBatch{
+		conn:      conn,
+		buffers:   make([][]byte, count),
+		datagrams: make([][]byte, 0, count),
+		size:      size,
+	}
+	if udpConn, ok := conn.(*net.UDPConn); ok && segment {
+		b.udpConn = udpConn
+		b.segmented = make([]byte, count*size)
+		for i := range b.buffers {
+			b.buffers[i] = b.segmented[i*size : (i+1)*size]
+		}
+		b.segmentOOB = ethr.SegmentOOB(size)
+		b.oob = make([]byte, ethr.SegmentOOBLen)
+		return b
+	}
+	b.messages = ethr.NewBatch(count, size)
+	for i := range b.messages {
+		b.buffers[i] = b.messages[i].Buffers[0]
+	}
+	if pc, ok := conn.(net.PacketConn); ok && count > 1 {
+		b.batch = ethr.NewBatchConn(pc)
+	}
+	return b
+}
+
+// send sends the first size bytes of count buffers and returns the number of
+// datagrams sent whole. Only a failing GSO send is returned as an error, the
+// datagrams are sent one at a time after it.
+func (b *datagramBatch) send(count, size int) (int, error) {
+	if count == 1 || (b.segmented != nil && (size != b.size || b.segmentErr != nil)) {
+		n, err := b.conn.Write(b.buffers[0][:size])
+		if err != nil || n < size {
+			return 0, nil
+		}
+		return 1, nil
+	}
+	if b.segmented != nil {
+		n, _, err := b.udpConn.WriteMsgUDP(b.segmented[:count*size], b.segmentOOB, nil)
+		if err != nil {
+			b.segmentErr = err
+			return 0, err
+		}
+		if n < count*size {
+			return 0, nil
+		}
+		return count, nil
+	}
+	for i := 0; i < count; i++ {
+		b.messages[i].Buffers[0] = b.buffers[i][:size]
+	}
+	sent, _ := b.batch.WriteBatch(b.messages[:count])
+	return sent, nil
+}
+
+// receive waits for datagrams and returns them, they are valid until the next
+// call.
+func (b *datagramBatch) receive() ([][]byte, error) {
+	b.datagrams = b.datagrams[:0]
+	if b.segmented != nil {
+		n, oobn, _, _, err := b.udpConn.ReadMsgUDP(b.segmented, b.oob)
+		if err != nil {
+			return nil, err
+		}
+		return ethr.AppendSegments(b.datagrams, b.segmented[:n], ethr.GROSegmentSize(b.oob[:oobn])), nil
+	}
+	if len(b.messages) == 1 {
+		n, err := b.conn.Read(b.buffers[0])
+		if err != nil {
+			return nil, err
+		}
+		return append(b.datagrams, b.buffers[0][:n]), nil
+	}
+	count, err := b.batch.ReadBatch(b.messages)
+	for _, m := range b.messages[:count] {
+		b.datagrams = append(b.datagrams, m.Buffers[0][:m.N])
+	}
+	return b.datagrams, err
+}
@@ -0,0 +1,186 @@
+package udp
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// echoTimeout is how long a datagram waits for its echo before it is considered
+// lost, echoes arriving later are counted as late instead. Only echoes of the
+// last lateWindow datagrams that timed out are recognized as late.
+const (
+	echoTimeout = time.Second
+	lateWindow  = 1024
+)
+
+type echo struct {
+	sequence uint64
+	received time.Time
+}
+
+// TestLatency sends sequence-numbered datagrams of BufferSize bytes one at a time
+// and waits for the server to echo each of them back.
+func (t Tests) TestLatency(test *session.Test, g time.Duration) {
+	conn, err := t.NetTools.Dial(ethr.UDP, test.DialAddr, t.NetTools.LocalIP, t.NetTools.LocalPort, 0, 0)
+	if err != nil {
+		test.AddDirectResult(session.TestResult{
+			Success: false,
+			Error:   fmt.Errorf("unable to dial UDP connection to %s: %w", test.DialAddr, err),
+			Body:    nil,
+		})
+		return
+	}
+	defer conn.Close()
+
+	echoes := make(chan echo, 64)
+	go receiveEchoes(test, conn, echoes)
+
+	buffSize := test.ClientParam.BufferSize
+	if buffSize < ethr.DatagramHeaderLen {
+		buffSize = ethr.DatagramHeaderLen
+	}
+	buff := make([]byte, buffSize)
+	// the header at the start of each datagram is left out of the content
+	content := buff[ethr.DatagramHeaderLen:]
+	filler, err := test.ClientParam.Content.NewFiller()
+	if err != nil {
+		test.AddDirectResult(session.TestResult{
+			Success: false,
+			Error:   err,
+			Body:    nil,
+		})
+		return
+	}
+	filler.Fill(content)
+	rttCount := test.ClientParam.RttCount
+	sequence := uint64(0)
+	timedOut := make(map[uint64]struct{})
+	for {
+	ExitSelect:
+		select {
+		case <-test.Done:
+			return
+		default:
+			raw := payloads.RawUDPLatencies{
+				Latencies: make([]time.Duration, 0, rttCount),
+			}
+			t0 := time.Now()
+			for i := uint32(0); i < rttCount; i++ {
+				sequence++
+				if !filler.Static() {
+					filler.Fill(content)
+				}
+				s1 := time.Now()
+				ethr.DatagramHeader{Type: ethr.DatagramEcho, Sequence: sequence, SendTime: s1.UnixNano()}.Encode(buff)
+				_, err = conn.Write(buff)
+				if err != nil {
+					test.AddDirectResult(session.TestResult{
+						Success: false,
+						Error:   fmt.Errorf("error sending UDP datagram: %w", err),
+						Body:    nil,
+					})
+					break ExitSelect
+				}
+				raw.Sent++
+
+				timeout := time.NewTimer(echoTimeout)
+			WaitEcho:
+				for {
+					select {
+					case e := <-echoes:
+						if e.sequence == sequence {
+							raw.Latencies = append(raw.Latencies, e.received.Sub(s1))
+							break WaitEcho
+						}
+						// echo of a datagram already given up on, anything else is a duplicate.
+						// It stays out of the latencies, those are bounded by echoTimeout.
+						if _, ok := timedOut[e.sequence]; ok {
+							delete(timedOut, e.sequence)
+							raw.Late++
+						}
+					case <-timeout.C:
+						timedOut[sequence] = struct{}{}
+						delete(timedOut, sequence-lateWindow)
+						raw.TimedOut++
+						break WaitEcho
+					case <-test.Done:
+						timeout.Stop()
+						return
+					}
+				}
+				timeout.Stop()
+			}
+
+			test.AddIntermediateResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body:    raw,
+			})
+			t1 := time.Since(t0)
+			if t1 < g {
+				time.Sleep(g - t1)
+			}
+		}
+	}
+}
+
+func receiveEchoes(test *session.Test, conn net.Conn, echoes chan<- echo) {
+	buff := make([]byte, 64*1024)
+	for {
+		n, err := conn.Read(buff)
+		received := time.Now()
+		if err != nil {
+			select {
+			case <-test.Done:
+				return
+			default:
+				// connection refused is reported until the server is up, keep reading
+				continue
+			}
+		}
+		header, ok := ethr.DecodeDatagramHeader(buff[:n])
+		if !ok || header.Type != ethr.DatagramEcho {
+			continue
+		}
+		select {
+		case echoes <- echo{sequence: header.Sequence, received: received}:
+		case <-test.Done:
+			return
+		}
+	}
+}
+
+func LatencyAggregator(nanos uint64, intermediateResults []session.TestResult) session.TestResult {
+	latencies := make([]time.Duration, 0)
+	sent, timedOut, late := uint64(0), uint64(0), uint64(0)
+	for _, r := range intermediateResults {
+		// ignore failed results
+		if body, ok := r.Body.(payloads.RawUDPLatencies); ok && r.Success {
+			latencies = append(latencies, body.Latencies...)
+			sent += body.Sent
+			timedOut += body.TimedOut
+			late += body.Late
+		}
+	}
+
+	// late echoes may belong to datagrams that timed out in an earlier interval
+	lost := uint64(0)
+	if timedOut > late {
+		lost = timedOut - late
+	}
+	return session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body: payloads.UDPLatencyPayload{
+			Latency: payloads.NewLatencies(latencies),
+			Sent:    sent,
+			Lost:    lost,
+			Late:    late,
+		},
+	}
+}
@@ -0,0 +1,244 @@
+package udp
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// discoveryAttempts is the number of probes a flow sends to a hop that doesn't
+// answer while discovering paths.
+const discoveryAttempts = 2
+
+// traceMultipath probes every hop with all flows of the tracer. Load balancers
+// send the flows along different equal-cost paths, the routers seen at each hop
+// and the links between them make up the multipath graph. Hops keeps the path
+// of the first flow.
+func (t Tests) traceMultipath(tr *tracer, gap time.Duration, mtrMode bool, maxHops int) {
+	test := tr.test
+	paths, err := t.discoverPaths(tr, maxHops)
+	hops := paths[0]
+	if err != nil {
+		test.AddDirectResult(session.TestResult{
+			Success: false,
+			Error:   fmt.Errorf("destination (%s) not responding to UDP probes", test.RemoteIP),
+			Body:    payloads.TraceRoutePayload{Hops: hops},
+		})
+		test.Terminate()
+		return
+	}
+	multipath := t.buildMultipath(paths)
+	if !mtrMode {
+		test.AddDirectResult(session.TestResult{
+			Success: true,
+			Error:   nil,
+			Body:    payloads.TraceRoutePayload{Hops: hops, Multipath: multipath},
+		})
+		test.Terminate()
+		return
+	}
+
+	for i := range multipath {
+		for j := range multipath[i].Routers {
+			router := &multipath[i].Routers[j]
+			if router.Addr == nil {
+				continue
+			}
+			for _, id := range router.Flows {
+				var hop *payloads.NetworkHop
+				if id == 0 {
+					hop = &hops[i]
+				}
+				go t.probeRouter(tr, tr.flows[id], gap, i, router, hop)
+			}
+		}
+	}
+	resultsTicker := time.NewTicker(time.Second)
+	defer resultsTicker.Stop()
+	for {
+		select {
+		case <-resultsTicker.C:
+			test.AddDirectResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body:    tr.snapshot(hops, multipath),
+			})
+		case <-test.Done:
+			return
+		}
+	}
+}
+
+// discoverPaths returns the hops seen by every flow, hops are probed by all flows
+// at once. Routers limit the rate of their ICMP errors, flows without an answer
+// are probed again once the first probes timed out. The first flow reports its
+// hops like a single path traceroute.
+func (t Tests) discoverPaths(tr *tracer, maxHops int) ([][]payloads.NetworkHop, error) {
+	paths := make([][]payloads.NetworkHop, len(tr.flows))
+	finished := make([]bool, len(tr.flows))
+	reached := false
+	for i := 0; i < maxHops; i++ {
+		round := make([]payloads.NetworkHop, len(tr.flows))
+		for attempt := 0; attempt < discoveryAttempts; attempt++ {
+			var wg sync.WaitGroup
+			for id, f := range tr.flows {
+				if finished[id] || round[id].Rcvd > 0 {
+					continue
+				}
+				wg.Add(1)
+				go func(id int, f *flow) {
+					defer wg.Done()
+					hop := &round[id]
+					hop.HopNumber = i
+					err, isLast := t.probeHop(tr, f, i+1, "", hop)
+					if err == nil {
+						name := t.NetTools.LookupHopName(hop.Addr.String())
+						hop.Name, hop.FullName = name, name
+					}
+					finished[id] = isLast
+				}(id, f)
+			}
+			wg.Wait()
+		}
+
+		for id := range tr.flows {
+			if len(paths[id]) == i {
+				paths[id] = append(paths[id], round[id])
+			}
+		}
+		if len(paths[0]) == i+1 {
+			tr.test.AddIntermediateResult(session.TestResult{
+				Success: false,
+				Error:   nil,
+				Body:    paths[0][i],
+			})
+		}
+		done := true
+		for _, f := range finished {
+			reached = reached || f
+			done = done && f
+		}
+		if done {
+			break
+		}
+	}
+	if !reached {
+		return paths, fmt.Errorf("no flow reached the destination")
+	}
+	return paths, nil
+}
+
+// buildMultipath merges the paths of all flows into the routers seen at each hop.
+func (t Tests) buildMultipath(paths [][]payloads.NetworkHop) []payloads.MultipathHop {
+	maxLen := 0
+	for _, p := range paths {
+		if len(p) > maxLen {
+			maxLen = len(p)
+		}
+	}
+
+	multipath := make([]payloads.MultipathHop, maxLen)
+	for i := range multipath {
+		multipath[i].HopNumber = i
+		index := make(map[string]int)
+		for id, p := range paths {
+			if i >= len(p) {
+				continue
+			}
+			hop := p[i]
+			key := addrString(hop.Addr)
+			j, ok := index[key]
+			if !ok {
+				j = len(multipath[i].Routers)
+				index[key] = j
+				multipath[i].Routers = append(multipath[i].Routers, payloads.MultipathRouter{
+					NetworkHop: payloads.NetworkHop{
+						HopNumber: i,
+						Addr:      hop.Addr,
+						Name:      hop.Name,
+						FullName:  hop.FullName,
+					},
+				})
+			}
+			router := &multipath[i].Routers[j]
+			router.Flows = append(router.Flows, id)
+			mergeProbes(&router.NetworkHop, hop)
+			if i+1 < len(p) && p[i+1].Addr != nil {
+				router.Next = appendUnique(router.Next, p[i+1].Addr.String())
+			}
+		}
+	}
+	return multipath
+}
+
+// probeRouter keeps probing a router along the path of one flow, hop is the same
+// router in the path of the first flow.
+func (t Tests) probeRouter(tr *tracer, f *flow, gap time.Duration, ttl int, router *payloads.MultipathRouter, hop *payloads.NetworkHop) {
+	hopIP := router.Addr.String()
+	for {
+		select {
+		case <-tr.test.Done:
+			return
+		default:
+			t0 := time.Now()
+			probes := payloads.NetworkHop{}
+			_, _ = t.probeHop(tr, f, ttl+1, hopIP, &probes)
+			tr.statsLock.Lock()
+			mergeProbes(&router.NetworkHop, probes)
+			if hop != nil {
+				mergeProbes(hop, probes)
+			}
+			tr.statsLock.Unlock()
+			t1 := time.Since(t0)
+			if t1 < gap {
+				time.Sleep(gap - t1)
+			}
+		}
+	}
+}
+
+// snapshot copies the results so they can be published while probes go on.
+func (tr *tracer) snapshot(hops []payloads.NetworkHop, multipath []payloads.MultipathHop) payloads.TraceRoutePayload {
+	tr.statsLock.Lock()
+	defer tr.statsLock.Unlock()
+	p := payloads.TraceRoutePayload{
+		Hops:      append([]payloads.NetworkHop(nil), hops...),
+		Multipath: make([]payloads.MultipathHop, len(multipath)),
+	}
+	for i, hop := range multipath {
+		p.Multipath[i] = payloads.MultipathHop{
+			HopNumber: hop.HopNumber,
+			Routers:   append([]payloads.MultipathRouter(nil), hop.Routers...),
+		}
+	}
+	return p
+}
+
+// mergeProbes adds a single round of probes to the statistics of a router.
+func mergeProbes(dst *payloads.NetworkHop, probes payloads.NetworkHop) {
+	dst.Sent += probes.Sent
+	dst.Lost += probes.Lost
+	if probes.Rcvd > 0 {
+		dst.UpdateStats(nil, probes.Last)
+	}
+}
+
+func addrString(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	return addr.String()
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, l := range list {
+		if l == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
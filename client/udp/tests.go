@@ -1,7 +1,23 @@
 package udp
 
-import "weavelab.xyz/ethr/client/tools"
+import (
+	"time"
+
+	"weavelab.xyz/ethr/client/tools"
+	"weavelab.xyz/ethr/ethr"
+)
+
+// The final report of a UDP stream is requested reportAttempts times, waiting
+// reportTimeout for the server to answer. Streams sent by the server are kept
+// going by a start datagram every keepaliveInterval.
+const (
+	reportAttempts    = 3
+	reportTimeout     = 500 * time.Millisecond
+	keepaliveInterval = 500 * time.Millisecond
+	receiveBufferSize = 4 * 1024 * 1024
+)
 
 type Tests struct {
 	NetTools *tools.Tools
+	Logger   ethr.Logger
 }
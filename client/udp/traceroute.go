@@ -0,0 +1,419 @@
+package udp
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// parisChecksum is the UDP checksum of every probe in Paris mode, routers hashing
+// the first bytes of the UDP header then see a single flow.
+const parisChecksum = 0x4554
+
+const probeTimeout = 2 * time.Second
+
+// tracer sends the probes of a UDP traceroute. In Paris mode all probes of a
+// flow go through the same socket so their 5-tuple doesn't change, otherwise
+// every hop uses its own source port like TCP traceroute. Multipath discovery
+// sends the probes of several flows.
+type tracer struct {
+	test  *session.Test
+	flows []*flow
+
+	// multipath routers are updated by the probes of several flows
+	statsLock sync.Mutex
+}
+
+type flow struct {
+	id int
+
+	// the TTL of the shared socket is changed for every probe
+	lock sync.Mutex
+	conn *net.UDPConn
+
+	// the echoes read from the socket are handed to the probe they answer by ID,
+	// hops of mtr and flows probe the same socket at once
+	probesLock sync.Mutex
+	lastProbe  uint64
+	probes     map[uint64]chan<- basicHop
+}
+
+func newFlow(id int, conn *net.UDPConn) *flow {
+	f := &flow{id: id, conn: conn, probes: make(map[uint64]chan<- basicHop)}
+	go f.receiveEchoes()
+	return f
+}
+
+// addProbe returns the ID of a new probe whose echo is sent to replies.
+func (f *flow) addProbe(replies chan<- basicHop) uint64 {
+	f.probesLock.Lock()
+	defer f.probesLock.Unlock()
+	f.lastProbe++
+	f.probes[f.lastProbe] = replies
+	return f.lastProbe
+}
+
+func (f *flow) removeProbe(id uint64) {
+	f.probesLock.Lock()
+	defer f.probesLock.Unlock()
+	delete(f.probes, id)
+}
+
+// receiveEchoes reads the socket of the flow until it is closed.
+func (f *flow) receiveEchoes() {
+	buff := make([]byte, 64*1024)
+	for {
+		n, err := f.conn.Read(buff)
+		if err != nil {
+			// Go doesn't export the error of closed connections yet
+			if strings.Contains(err.Error(), "use of closed network") {
+				return
+			}
+			// ICMP errors for earlier probes are reported on the socket
+			continue
+		}
+		header, ok := ethr.DecodeDatagramHeader(buff[:n])
+		if !ok || header.Type != ethr.DatagramEcho {
+			continue
+		}
+		f.probesLock.Lock()
+		replies, ok := f.probes[header.Sequence]
+		f.probesLock.Unlock()
+		if ok {
+			select {
+			case replies <- basicHop{addr: &net.IPAddr{IP: f.conn.RemoteAddr().(*net.UDPAddr).IP}, endTime: time.Now(), isLast: true}:
+			default:
+			}
+		}
+	}
+}
+
+func (t Tests) TestTraceRoute(test *session.Test, gap time.Duration, mtrMode bool, maxHops int) {
+	numFlows := int(test.ClientParam.Flows)
+	tr := &tracer{test: test}
+	if test.ClientParam.Paris || numFlows > 1 {
+		if numFlows < 1 {
+			numFlows = 1
+		}
+		for i := 0; i < numFlows; i++ {
+			conn, err := t.NetTools.Dial(ethr.UDP, test.DialAddr, nil, t.NetTools.LocalPort+uint16(i), 0, 0)
+			if err != nil {
+				test.AddDirectResult(session.TestResult{
+					Success: false,
+					Error:   fmt.Errorf("failed to create UDP socket for traceroute: %w", err),
+					Body:    payloads.TraceRoutePayload{},
+				})
+				test.Terminate()
+				return
+			}
+			defer conn.Close()
+			tr.flows = append(tr.flows, newFlow(i, conn.(*net.UDPConn)))
+		}
+	}
+	if len(tr.flows) > 1 {
+		t.traceMultipath(tr, gap, mtrMode, maxHops)
+		return
+	}
+
+	var f *flow
+	if len(tr.flows) > 0 {
+		f = tr.flows[0]
+	}
+	hops, err := t.discoverHops(tr, f, maxHops)
+	if err != nil {
+		test.AddDirectResult(session.TestResult{
+			Success: false,
+			Error:   fmt.Errorf("destination (%s) not responding to UDP probes", test.RemoteIP),
+			Body:    payloads.TraceRoutePayload{Hops: hops},
+		})
+		test.Terminate()
+		return
+	}
+	if !mtrMode {
+		test.AddDirectResult(session.TestResult{
+			Success: true,
+			Error:   nil,
+			Body:    payloads.TraceRoutePayload{Hops: hops},
+		})
+		test.Terminate()
+		return
+	}
+	for i := 0; i < len(hops); i++ {
+		if hops[i].Addr != nil && hops[i].Addr.String() != "" {
+			go t.probeHops(tr, f, gap, i, hops)
+		}
+	}
+	resultsTicker := time.NewTicker(time.Second)
+	defer resultsTicker.Stop()
+	for {
+		select {
+		case <-resultsTicker.C:
+			test.AddDirectResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body:    payloads.TraceRoutePayload{Hops: hops},
+			})
+		case <-test.Done:
+			return
+		}
+	}
+}
+
+func (t Tests) probeHops(tr *tracer, f *flow, gap time.Duration, hop int, hops []payloads.NetworkHop) {
+	for {
+		select {
+		case <-tr.test.Done:
+			return
+		default:
+			t0 := time.Now()
+			_, _ = t.probeHop(tr, f, hop+1, hops[hop].Addr.String(), &hops[hop])
+			t1 := time.Since(t0)
+			if t1 < gap {
+				time.Sleep(gap - t1)
+			}
+		}
+	}
+}
+
+func (t Tests) discoverHops(tr *tracer, f *flow, maxHops int) ([]payloads.NetworkHop, error) {
+	hops := make([]payloads.NetworkHop, maxHops)
+	for i := 0; i < maxHops; i++ {
+		hop := payloads.NetworkHop{
+			HopNumber: i,
+		}
+		err, isLast := t.probeHop(tr, f, i+1, "", &hop)
+		if err != nil && errors.Is(err, syscall.EPERM) {
+			return nil, err
+		}
+		if err == nil {
+			name := t.NetTools.LookupHopName(hop.Addr.String())
+			hop.Name, hop.FullName = name, name
+		}
+		hops[i] = hop
+		tr.test.AddIntermediateResult(session.TestResult{
+			Success: false,
+			Error:   nil,
+			Body:    hop,
+		})
+		if isLast {
+			return hops[:i+1], nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+type basicHop struct {
+	addr    net.Addr
+	endTime time.Time
+	isLast  bool
+}
+
+// probeHop sends an echo datagram with the given TTL on the socket of flow f, or
+// a new one without a flow. Routers on the way answer with ICMP Time Exceeded,
+// the Ethr server echoes it back and other destinations answer with ICMP Port
+// Unreachable.
+func (t Tests) probeHop(tr *tracer, f *flow, hop int, hopIP string, hopData *payloads.NetworkHop) (error, bool) {
+	icmpConn, err := t.NetTools.IcmpNewConn(tr.test.RemoteIP.String())
+	if err != nil {
+		return fmt.Errorf("failed to create ICMP connection: %w", err), false
+	}
+	defer icmpConn.Close()
+
+	replies := make(chan basicHop, 2)
+	var conn *net.UDPConn
+	var probe []byte
+	if f != nil {
+		conn = f.conn
+		id := f.addProbe(replies)
+		defer f.removeProbe(id)
+		probe = t.newProbe(conn, hop, id, true)
+	} else {
+		c, err := t.NetTools.Dial(ethr.UDP, tr.test.DialAddr, nil, t.NetTools.LocalPort+uint16(hop), hop, 0)
+		if err != nil {
+			return fmt.Errorf("failed to create UDP socket for hop %d: %w", hop, err), false
+		}
+		defer c.Close()
+		conn = c.(*net.UDPConn)
+		probe = t.newProbe(conn, hop, uint64(hop), false)
+		go receiveEcho(conn, probe, replies)
+	}
+	go t.receiveTimeExceeded(icmpConn, conn, len(probe), hopIP, replies)
+
+	startTime := time.Now()
+	if f != nil {
+		f.lock.Lock()
+		err = setTTL(conn, t.NetTools.IPVersion, hop)
+		if err == nil {
+			_, err = conn.Write(probe)
+		}
+		f.lock.Unlock()
+	} else {
+		_, err = conn.Write(probe)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to send UDP probe for hop %d: %w", hop, err), false
+	}
+
+	var nextHop basicHop
+	timeout := time.NewTimer(probeTimeout)
+	select {
+	case nextHop = <-replies:
+	case <-timeout.C:
+	}
+	timeout.Stop()
+
+	hopData.Sent++
+	if nextHop.addr == nil || nextHop.addr.String() == "" || (hopIP != "" && nextHop.addr.String() != hopIP) {
+		hopData.Lost++
+		return fmt.Errorf("failed to receive echo or ICMP TTL Exceeded: %w", os.ErrNotExist), false
+	}
+	hopData.UpdateStats(nextHop.addr, nextHop.endTime.Sub(startTime))
+	return nil, nextHop.isLast
+}
+
+// newProbe builds an echo datagram whose length identifies the hop and whose
+// sequence identifies its echo, in Paris mode two bytes after the header keep the
+// checksum at parisChecksum.
+func (t Tests) newProbe(conn *net.UDPConn, hop int, id uint64, paris bool) []byte {
+	probe := make([]byte, ethr.DatagramHeaderLen+2+hop)
+	ethr.DatagramHeader{Type: ethr.DatagramEcho, Sequence: id}.Encode(probe)
+	if paris {
+		local, _ := conn.LocalAddr().(*net.UDPAddr)
+		remote, _ := conn.RemoteAddr().(*net.UDPAddr)
+		if local != nil && remote != nil {
+			sum := udpChecksumSum(local, remote, probe)
+			binary.BigEndian.PutUint16(probe[ethr.DatagramHeaderLen:], onesComplementAdd(^uint16(parisChecksum), ^sum))
+		}
+	}
+	return probe
+}
+
+// receiveTimeExceeded waits for the ICMP error quoting the probe sent on conn,
+// the ports and the length in the quoted UDP header identify it.
+func (t Tests) receiveTimeExceeded(icmpConn net.PacketConn, conn *net.UDPConn, probeLen int, hopIP string, replies chan<- basicHop) {
+	local, _ := conn.LocalAddr().(*net.UDPAddr)
+	remote, _ := conn.RemoteAddr().(*net.UDPAddr)
+	if local == nil || remote == nil {
+		return
+	}
+	deadline := time.Now().Add(probeTimeout)
+	for time.Now().Before(deadline) {
+		icmpMsg, peer, err := t.NetTools.ReceiveICMPFromPeer(icmpConn, time.Until(deadline), hopIP)
+		if err != nil {
+			// Go doesn't export the error of closed connections yet
+			if errors.Is(err, os.ErrDeadlineExceeded) || strings.Contains(err.Error(), "use of closed network") {
+				return
+			}
+			continue
+		}
+
+		var quoted []byte
+		isLast := false
+		switch body := icmpMsg.Body.(type) {
+		case *icmp.TimeExceeded:
+			quoted = body.Data
+		case *icmp.DstUnreach:
+			// no way past it, for the destination this is Port Unreachable
+			quoted = body.Data
+			isLast = true
+		default:
+			continue
+		}
+		udpHeader := quotedUDPHeader(quoted, t.NetTools.IPVersion)
+		if udpHeader == nil ||
+			int(binary.BigEndian.Uint16(udpHeader[0:])) != local.Port ||
+			int(binary.BigEndian.Uint16(udpHeader[2:])) != remote.Port ||
+			int(binary.BigEndian.Uint16(udpHeader[4:])) != probeLen+8 {
+			continue
+		}
+		replies <- basicHop{addr: peer, endTime: time.Now(), isLast: isLast}
+		return
+	}
+}
+
+func receiveEcho(conn *net.UDPConn, probe []byte, replies chan<- basicHop) {
+	buff := make([]byte, len(probe)+1)
+	_ = conn.SetReadDeadline(time.Now().Add(probeTimeout))
+	for {
+		n, err := conn.Read(buff)
+		if err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) || strings.Contains(err.Error(), "use of closed network") {
+				return
+			}
+			// ICMP errors for earlier probes are reported on the socket
+			continue
+		}
+		if n == len(probe) && string(buff[:n]) == string(probe) {
+			replies <- basicHop{addr: &net.IPAddr{IP: conn.RemoteAddr().(*net.UDPAddr).IP}, endTime: time.Now(), isLast: true}
+			return
+		}
+	}
+}
+
+// quotedUDPHeader returns the UDP header of the IP packet quoted in ICMP errors.
+func quotedUDPHeader(quoted []byte, v ethr.IPVersion) []byte {
+	headerLen := ipv6.HeaderLen
+	if v != ethr.IPv6 {
+		if len(quoted) < ipv4.HeaderLen {
+			return nil
+		}
+		headerLen = int(quoted[0]&0x0f) << 2
+	}
+	if len(quoted) < headerLen+8 {
+		return nil
+	}
+	return quoted[headerLen : headerLen+8]
+}
+
+func setTTL(conn *net.UDPConn, v ethr.IPVersion, ttl int) error {
+	if v == ethr.IPv6 {
+		return ipv6.NewConn(conn).SetHopLimit(ttl)
+	}
+	return ipv4.NewConn(conn).SetTTL(ttl)
+}
+
+// udpChecksumSum is the ones' complement sum the UDP checksum of payload is
+// computed from, including the pseudo header and a zero checksum field.
+func udpChecksumSum(local, remote *net.UDPAddr, payload []byte) uint16 {
+	length := 8 + len(payload)
+	b := make([]byte, 0, 40+length)
+	if ip := local.IP.To4(); ip != nil && remote.IP.To4() != nil {
+		b = append(b, ip...)
+		b = append(b, remote.IP.To4()...)
+		b = append(b, 0, syscall.IPPROTO_UDP, byte(length>>8), byte(length))
+	} else {
+		b = append(b, local.IP.To16()...)
+		b = append(b, remote.IP.To16()...)
+		b = append(b, 0, 0, byte(length>>8), byte(length), 0, 0, 0, syscall.IPPROTO_UDP)
+	}
+	b = append(b, byte(local.Port>>8), byte(local.Port), byte(remote.Port>>8), byte(remote.Port), byte(length>>8), byte(length), 0, 0)
+	b = append(b, payload...)
+	if len(b)%2 == 1 {
+		b = append(b, 0)
+	}
+
+	sum := uint16(0)
+	for i := 0; i < len(b); i += 2 {
+		sum = onesComplementAdd(sum, binary.BigEndian.Uint16(b[i:]))
+	}
+	return sum
+}
+
+func onesComplementAdd(a, b uint16) uint16 {
+	s := uint32(a) + uint32(b)
+	return uint16(s&0xffff + s>>16)
+}
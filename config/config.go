@@ -8,6 +8,7 @@ import (
 	"os"
 	"regexp"
 	"runtime"
+	"strings"
 	"time"
 
 	"weavelab.xyz/ethr/ui"
@@ -27,9 +28,13 @@ var (
 	Port       uint16
 	LocalIP    net.IP
 	IsServer   bool
+	// UDP datagrams moved per sendmmsg and recvmmsg call
+	BatchSize int
 
 	// Server Only
-	ShowUI bool
+	ShowUI   bool
+	CertFile string
+	KeyFile  string
 
 	// Client Only
 	ClientDest         string
@@ -41,8 +46,36 @@ var (
 	Gap                time.Duration
 	Iterations         int
 	NoConnectionStats  bool
+	NoKeepAlive        bool
+	IgnoreCert         bool
+	TLSVersions        []uint16
+	CipherSuites       []uint16
 	Protocol           ethr.Protocol
 	Reverse            bool
+	Bidirectional      bool
+	Paris              bool
+	Flows              int
+	CongestionControl  []string
+	Nagle              bool
+	SendBuffer         uint64
+	ReceiveBuffer      uint64
+	MSS                int
+	SendMode           ethr.SendMode
+	GSO                bool
+	Pacing             ethr.PaceMode
+	Burst              uint64
+	SharedRate         bool
+	Profile            ethr.TrafficProfile
+	Sizes              ethr.SizeDistribution
+	Content            ethr.Content
+	ResponseSize       uint64
+	RampRate           int
+	MaxConnections     int
+	KeepActive         time.Duration
+	Verify             bool
+	VerifySeed         int64
+	Linger             int
+	SetLinger          bool
 	TestType           ethr.TestType
 	TOS                int
 	Title              string
@@ -57,6 +90,12 @@ var (
 
 var hasPortRegex = regexp.MustCompile(".+(:\\d+)")
 
+// maxFlows limits multipath discovery, every flow uses its own source port.
+const maxFlows = 64
+
+// maxBatchSize is the most datagrams a single sendmmsg or recvmmsg call takes.
+const maxBatchSize = 1024
+
 func Init() error {
 	flag.Usage = func() { Usage() }
 	flag.BoolVar(&NoOutput, "no", false, "")
@@ -67,8 +106,11 @@ func Init() error {
 	port := flag.Int("port", 9999, "")
 	rawIP := flag.String("ip", "localhost", "")
 	flag.BoolVar(&IsServer, "s", false, "")
+	flag.IntVar(&BatchSize, "batch", 1, "")
 
 	flag.BoolVar(&ShowUI, "ui", false, "")
+	flag.StringVar(&CertFile, "cert", "", "")
+	flag.StringVar(&KeyFile, "key", "", "")
 
 	flag.StringVar(&ClientDest, "c", "", "")
 	bufferLen := flag.String("l", "", "")
@@ -78,8 +120,34 @@ func Init() error {
 	flag.DurationVar(&Gap, "g", time.Second, "")
 	flag.IntVar(&Iterations, "i", 1000, "")
 	flag.BoolVar(&NoConnectionStats, "ncs", false, "")
+	flag.BoolVar(&NoKeepAlive, "nka", false, "")
+	flag.BoolVar(&IgnoreCert, "ic", false, "")
+	rawTLSVersions := flag.String("tls", "", "")
+	rawCipherSuites := flag.String("ciphers", "", "")
 	rawProtocol := flag.String("p", "tcp", "")
 	flag.BoolVar(&Reverse, "r", false, "")
+	flag.BoolVar(&Bidirectional, "bidir", false, "")
+	flag.BoolVar(&Paris, "paris", false, "")
+	flag.IntVar(&Flows, "flows", 0, "")
+	rawCongestionControl := flag.String("cc", "", "")
+	flag.BoolVar(&Nagle, "nagle", false, "")
+	sendBuffer := flag.String("sndbuf", "", "")
+	receiveBuffer := flag.String("rcvbuf", "", "")
+	flag.IntVar(&MSS, "mss", 0, "")
+	flag.IntVar(&Linger, "linger", 0, "")
+	rawSendMode := flag.String("zc", "", "")
+	flag.BoolVar(&GSO, "gso", false, "")
+	rawPacing := flag.String("pace", "", "")
+	burst := flag.String("burst", "", "")
+	flag.BoolVar(&SharedRate, "bshare", false, "")
+	rawProfile := flag.String("profile", "", "")
+	rawSizes := flag.String("sizes", "", "")
+	rawContent := flag.String("payload", "", "")
+	responseLen := flag.String("resp", "", "")
+	flag.IntVar(&RampRate, "ramp", 1000, "")
+	flag.IntVar(&MaxConnections, "maxconn", 0, "")
+	flag.DurationVar(&KeepActive, "active", 0, "")
+	flag.BoolVar(&Verify, "verify", false, "")
 	rawTestType := flag.String("t", "b", "")
 	flag.IntVar(&TOS, "tos", 0, "")
 	flag.StringVar(&Title, "T", "", "")
@@ -145,8 +213,11 @@ func Init() error {
 
 	if !IsServer {
 		if *bufferLen == "" {
-			if TestType == ethr.TestTypeLatency || TestType == ethr.TestTypePacketsPerSecond {
+			if TestType == ethr.TestTypeLatency || TestType == ethr.TestTypePacketsPerSecond ||
+				TestType == ethr.TestTypeRequestResponse || TestType == ethr.TestTypeConnectRequestResponse {
 				BufferSize = ui.UnitToNumber("1B")
+			} else if Protocol == ethr.HTTP || Protocol == ethr.HTTPS {
+				BufferSize = ui.UnitToNumber("1MB")
 			} else {
 				BufferSize = ui.UnitToNumber("16KB")
 			}
@@ -156,14 +227,99 @@ func Init() error {
 		if BufferSize == 0 {
 			return errors.New("invalid buffer size")
 		}
+		// responses are as large as requests unless told otherwise
+		ResponseSize = BufferSize
+		if *responseLen != "" {
+			ResponseSize = ui.UnitToNumber(*responseLen)
+			if ResponseSize == 0 {
+				return errors.New("invalid response size")
+			}
+		}
+		Sizes, err = parseSizes(*rawSizes)
+		if err != nil {
+			return fmt.Errorf("invalid argument for \"-sizes\": %w", err)
+		}
+		if Sizes.IsSet() {
+			if *bufferLen != "" {
+				return errors.New("the buffer holds the largest of the message sizes (-sizes), -l can't be used with them")
+			}
+			BufferSize = uint64(Sizes.Max())
+		}
+		if Verify {
+			VerifySeed = time.Now().UnixNano()
+		}
+		Content, err = parseContent(*rawContent)
+		if err != nil {
+			return fmt.Errorf("invalid argument for \"-payload\": %w", err)
+		}
 
 		if *bw != "" {
 			BandwidthRate = ui.UnitToNumber(*bw) / 8
 		}
+		Profile, err = parseProfile(*rawProfile, Duration)
+		if err != nil {
+			return fmt.Errorf("invalid argument for \"-profile\": %w", err)
+		}
+		if Profile.Kind == ethr.ProfileRamp || Profile.Kind == ethr.ProfileSteps {
+			if BandwidthRate != 0 {
+				return fmt.Errorf("the rates of %s profiles are part of -profile, -b can't be used with them", Profile.Kind)
+			}
+			// the peak rate sizes the bursts and marks the test as limited
+			for _, rate := range Profile.Rates {
+				if rate > BandwidthRate {
+					BandwidthRate = rate
+				}
+			}
+		}
+		if *burst != "" {
+			Burst = ui.UnitToNumber(*burst)
+			if Burst == 0 {
+				return errors.New("invalid burst size")
+			}
+		}
 
 		if ThreadCount == 0 {
 			ThreadCount = runtime.NumCPU()
 		}
+
+		// every iteration of an HTTPS latency test is a pair of handshakes per TLS profile
+		if Protocol == ethr.HTTPS && TestType == ethr.TestTypeLatency && !isFlagSet("i") {
+			Iterations = 10
+		}
+	}
+
+	if *rawCongestionControl != "" {
+		CongestionControl = strings.Split(*rawCongestionControl, ",")
+	}
+	if *sendBuffer != "" {
+		SendBuffer = ui.UnitToNumber(*sendBuffer)
+		if SendBuffer == 0 {
+			return errors.New("invalid send buffer size")
+		}
+	}
+	if *receiveBuffer != "" {
+		ReceiveBuffer = ui.UnitToNumber(*receiveBuffer)
+		if ReceiveBuffer == 0 {
+			return errors.New("invalid receive buffer size")
+		}
+	}
+	SetLinger = isFlagSet("linger")
+	SendMode, err = ethr.ParseSendMode(*rawSendMode)
+	if err != nil {
+		return fmt.Errorf("invalid argument for \"-zc\": %w", err)
+	}
+	Pacing, err = ethr.ParsePaceMode(*rawPacing)
+	if err != nil {
+		return fmt.Errorf("invalid argument for \"-pace\": %w", err)
+	}
+
+	TLSVersions, err = parseTLSVersions(*rawTLSVersions)
+	if err != nil {
+		return err
+	}
+	CipherSuites, err = parseCipherSuites(*rawCipherSuites)
+	if err != nil {
+		return err
 	}
 
 	Debug = true
@@ -203,12 +359,77 @@ func validateServerArgs() (err error) {
 	if NoConnectionStats {
 		invalidFlags = append(invalidFlags, "-ncs")
 	}
+	if NoKeepAlive {
+		invalidFlags = append(invalidFlags, "-nka")
+	}
 	if Protocol != ethr.TCP {
 		invalidFlags = append(invalidFlags, "-p")
 	}
 	if Reverse {
 		invalidFlags = append(invalidFlags, "-r")
 	}
+	if Bidirectional {
+		invalidFlags = append(invalidFlags, "-bidir")
+	}
+	if Paris {
+		invalidFlags = append(invalidFlags, "-paris")
+	}
+	if isFlagSet("flows") {
+		invalidFlags = append(invalidFlags, "-flows")
+	}
+	if len(CongestionControl) > 0 {
+		invalidFlags = append(invalidFlags, "-cc")
+	}
+	if Nagle {
+		invalidFlags = append(invalidFlags, "-nagle")
+	}
+	if SendBuffer != 0 {
+		invalidFlags = append(invalidFlags, "-sndbuf")
+	}
+	if ReceiveBuffer != 0 {
+		invalidFlags = append(invalidFlags, "-rcvbuf")
+	}
+	if MSS != 0 {
+		invalidFlags = append(invalidFlags, "-mss")
+	}
+	if SetLinger {
+		invalidFlags = append(invalidFlags, "-linger")
+	}
+	if SendMode != ethr.SendCopy {
+		invalidFlags = append(invalidFlags, "-zc")
+	}
+	if GSO {
+		invalidFlags = append(invalidFlags, "-gso")
+	}
+	if Pacing != ethr.PaceUser {
+		invalidFlags = append(invalidFlags, "-pace")
+	}
+	if isFlagSet("burst") {
+		invalidFlags = append(invalidFlags, "-burst")
+	}
+	if SharedRate {
+		invalidFlags = append(invalidFlags, "-bshare")
+	}
+	if isFlagSet("profile") {
+		invalidFlags = append(invalidFlags, "-profile")
+	}
+	if isFlagSet("sizes") {
+		invalidFlags = append(invalidFlags, "-sizes")
+	}
+	if isFlagSet("resp") {
+		invalidFlags = append(invalidFlags, "-resp")
+	}
+	if isFlagSet("payload") {
+		invalidFlags = append(invalidFlags, "-payload")
+	}
+	if Verify {
+		invalidFlags = append(invalidFlags, "-verify")
+	}
+	for _, name := range []string{"ramp", "maxconn", "active"} {
+		if isFlagSet(name) {
+			invalidFlags = append(invalidFlags, "-"+name)
+		}
+	}
 	if TestType != ethr.TestTypeServer {
 		invalidFlags = append(invalidFlags, "-t")
 	}
@@ -224,10 +445,25 @@ func validateServerArgs() (err error) {
 	if Title != "" {
 		invalidFlags = append(invalidFlags, "-T")
 	}
+	if IgnoreCert {
+		invalidFlags = append(invalidFlags, "-ic")
+	}
+	if len(TLSVersions) > 0 {
+		invalidFlags = append(invalidFlags, "-tls")
+	}
+	if len(CipherSuites) > 0 {
+		invalidFlags = append(invalidFlags, "-ciphers")
+	}
 
 	if len(invalidFlags) > 0 {
 		return fmt.Errorf("invalid command, %s can only be used in client (\"-c\") mode", invalidFlags)
 	}
+	if BatchSize < 1 || BatchSize > maxBatchSize {
+		return fmt.Errorf("batch size (-batch) must be between 1 and %d", maxBatchSize)
+	}
+	if (CertFile == "") != (KeyFile == "") {
+		return fmt.Errorf("both -cert and -key must be specified to use a server certificate")
+	}
 
 	return nil
 }
@@ -236,6 +472,9 @@ func validateClientArgs() error {
 	if ShowUI {
 		return fmt.Errorf("invalid argument, -ui can only be used in server (\"-s\") mode")
 	}
+	if CertFile != "" || KeyFile != "" {
+		return fmt.Errorf("invalid argument, -cert and -key can only be used in server (\"-s\") mode")
+	}
 	if ClientDest != "" && ExternalClientDest != "" {
 		return fmt.Errorf("invalid argument, both \"-c\" and \"-x\" cannot be specified at the same time")
 	}
@@ -257,7 +496,7 @@ func validateClientArgs() error {
 			}
 		} else if Protocol == ethr.ICMP {
 			switch TestType {
-			case ethr.TestTypePing, ethr.TestTypeTraceRoute, ethr.TestTypeMyTraceRoute:
+			case ethr.TestTypePing, ethr.TestTypeTraceRoute, ethr.TestTypeMyTraceRoute, ethr.TestTypeMTU:
 			default:
 				return unsupportedTest()
 			}
@@ -265,35 +504,175 @@ func validateClientArgs() error {
 			return unsupportedTest()
 		}
 	} else {
-		if Reverse && TestType != ethr.TestTypeBandwidth {
-			return fmt.Errorf("reverse mode (-r) is only supported for TCP Bandwidth tests")
+		if Reverse && !(TestType == ethr.TestTypeBandwidth || (Protocol == ethr.UDP && TestType == ethr.TestTypePacketsPerSecond)) {
+			return fmt.Errorf("reverse mode (-r) is only supported for Bandwidth and UDP Packets/s tests")
+		}
+		if Bidirectional && !((Protocol == ethr.TCP && TestType == ethr.TestTypeBandwidth) ||
+			(Protocol == ethr.UDP && (TestType == ethr.TestTypeBandwidth || TestType == ethr.TestTypePacketsPerSecond))) {
+			return fmt.Errorf("bidirectional mode (-bidir) is only supported for TCP Bandwidth and UDP Bandwidth and Packets/s tests")
+		}
+		if Bidirectional && Reverse {
+			return fmt.Errorf("invalid argument, both \"-r\" and \"-bidir\" cannot be specified at the same time")
+		}
+		if Paris && !(Protocol == ethr.UDP && (TestType == ethr.TestTypeTraceRoute || TestType == ethr.TestTypeMyTraceRoute)) {
+			return fmt.Errorf("paris mode (-paris) is only supported for UDP TraceRoute and MyTraceRoute tests")
+		}
+		if isFlagSet("flows") && !(Protocol == ethr.UDP && (TestType == ethr.TestTypeTraceRoute || TestType == ethr.TestTypeMyTraceRoute)) {
+			return fmt.Errorf("multipath discovery (-flows) is only supported for UDP TraceRoute and MyTraceRoute tests")
+		}
+		if isFlagSet("flows") && (Flows < 1 || Flows > maxFlows) {
+			return fmt.Errorf("number of flows (-flows) must be between 1 and %d", maxFlows)
+		}
+		if TestType == ethr.TestTypeLatencyUnderLoad && Duration <= 0 {
+			return fmt.Errorf("latency under load test (-t ll) runs in phases and can't run forever, use a duration (-d)")
+		}
+		isTransactions := TestType == ethr.TestTypeRequestResponse || TestType == ethr.TestTypeConnectRequestResponse
+		hasStreamOptions := len(CongestionControl) > 0 || Nagle || SendBuffer > 0 || ReceiveBuffer > 0 || MSS != 0 || SetLinger
+		if hasStreamOptions && !(Protocol == ethr.TCP &&
+			(TestType == ethr.TestTypeBandwidth || TestType == ethr.TestTypeLatency || TestType == ethr.TestTypeLatencyUnderLoad ||
+				isTransactions || TestType == ethr.TestTypeMaxConnections)) {
+			return fmt.Errorf("socket options (-cc, -nagle, -sndbuf, -rcvbuf, -mss and -linger) are only supported for TCP Bandwidth, Latency, Latency under load, request/response and max connections tests")
+		}
+		if isFlagSet("resp") && !isTransactions {
+			return fmt.Errorf("response size (-resp) is only supported for request/response tests (-t rr, crr)")
+		}
+		if (isFlagSet("ramp") || isFlagSet("maxconn") || isFlagSet("active")) && TestType != ethr.TestTypeMaxConnections {
+			return fmt.Errorf("-ramp, -maxconn and -active are only supported for max connections tests (-t mc)")
+		}
+		if RampRate <= 0 {
+			return fmt.Errorf("connections opened per second (-ramp) must be above 0")
+		}
+		if MaxConnections < 0 || KeepActive < 0 {
+			return fmt.Errorf("-maxconn and -active can't be negative")
+		}
+		if SendBuffer > ui.GIGA || ReceiveBuffer > ui.GIGA {
+			return fmt.Errorf("maximum socket buffer size (-sndbuf, -rcvbuf) is 1GB")
+		}
+		if isFlagSet("mss") && (MSS < 1 || MSS > 65535) {
+			return fmt.Errorf("MSS (-mss) must be between 1 and 65535")
+		}
+		if SendMode != ethr.SendCopy && !(Protocol == ethr.TCP && TestType == ethr.TestTypeBandwidth) {
+			return fmt.Errorf("zero-copy sending (-zc) is only supported for TCP Bandwidth tests")
+		}
+		if SendMode != ethr.SendCopy && Reverse {
+			return fmt.Errorf("zero-copy sending (-zc) can't be used with -r, the server sends in reverse mode")
+		}
+		if SendMode != ethr.SendCopy && runtime.GOOS != "linux" {
+			return fmt.Errorf("zero-copy sending (-zc) is only supported on Linux")
+		}
+		if BatchSize != 1 && !(Protocol == ethr.UDP &&
+			(TestType == ethr.TestTypeBandwidth || TestType == ethr.TestTypePacketsPerSecond)) {
+			return fmt.Errorf("batching (-batch) is only supported for UDP Bandwidth and Packets/s tests")
+		}
+		if BatchSize < 1 || BatchSize > maxBatchSize {
+			return fmt.Errorf("batch size (-batch) must be between 1 and %d", maxBatchSize)
+		}
+		if GSO && !(Protocol == ethr.UDP &&
+			(TestType == ethr.TestTypeBandwidth || TestType == ethr.TestTypePacketsPerSecond)) {
+			return fmt.Errorf("UDP segmentation offload (-gso) is only supported for UDP Bandwidth and Packets/s tests")
+		}
+		if GSO && runtime.GOOS != "linux" {
+			return fmt.Errorf("UDP segmentation offload (-gso) is only supported on Linux")
+		}
+		if GSO && BatchSize != 1 {
+			return fmt.Errorf("UDP segmentation offload (-gso) already sends many datagrams per system call, it can't be used with -batch")
+		}
+		if Pacing == ethr.PaceKernel && !((Protocol == ethr.TCP && TestType == ethr.TestTypeBandwidth) ||
+			(Protocol == ethr.UDP && (TestType == ethr.TestTypeBandwidth || TestType == ethr.TestTypePacketsPerSecond))) {
+			return fmt.Errorf("kernel pacing (-pace kernel) is only supported for TCP and UDP Bandwidth and UDP Packets/s tests")
+		}
+		if Pacing == ethr.PaceKernel && runtime.GOOS != "linux" {
+			return fmt.Errorf("kernel pacing (-pace kernel) is only supported on Linux")
+		}
+		if (Pacing != ethr.PaceUser || Burst != 0 || SharedRate || Profile.Kind != ethr.ProfileConstant) && BandwidthRate == 0 {
+			return fmt.Errorf("-pace, -burst, -bshare and -profile shape the rate limit, use -b to set one")
+		}
+		if Profile.Kind != ethr.ProfileConstant && !(TestType == ethr.TestTypeBandwidth ||
+			(Protocol == ethr.UDP && TestType == ethr.TestTypePacketsPerSecond)) {
+			return fmt.Errorf("traffic profiles (-profile) are only supported for Bandwidth and UDP Packets/s tests")
+		}
+		if Profile.Kind != ethr.ProfileConstant && Protocol == ethr.UDP && (Reverse || Bidirectional) {
+			return fmt.Errorf("traffic profiles (-profile) only shape what the client sends in UDP tests, they can't be used with -r or -bidir")
+		}
+		if Profile.Kind != ethr.ProfileConstant && Pacing == ethr.PaceKernel {
+			return fmt.Errorf("traffic profiles (-profile) change the rate over time, the kernel paces at a fixed rate")
+		}
+		if Burst > ui.GIGA {
+			return fmt.Errorf("maximum burst size (-burst) is 1GB")
+		}
+		if Sizes.IsSet() && !(Protocol == ethr.TCP && (TestType == ethr.TestTypeBandwidth || TestType == ethr.TestTypeLatency)) {
+			return fmt.Errorf("message size distributions (-sizes) are only supported for TCP Bandwidth and Latency tests")
+		}
+		if Verify && !(Protocol == ethr.TCP && TestType == ethr.TestTypeBandwidth) {
+			return fmt.Errorf("payload verification (-verify) is only supported for TCP Bandwidth tests")
+		}
+		if Verify && SendMode != ethr.SendCopy {
+			return fmt.Errorf("payload verification (-verify) writes every message before sending it, zero-copy sending (-zc) can't be used with it")
+		}
+		if isFlagSet("payload") && !((Protocol == ethr.TCP || Protocol == ethr.UDP) &&
+			(TestType == ethr.TestTypeBandwidth || TestType == ethr.TestTypeLatency)) {
+			return fmt.Errorf("payloads (-payload) are only supported for TCP and UDP Bandwidth and Latency tests")
+		}
+		if isFlagSet("payload") && Verify {
+			return fmt.Errorf("payload verification (-verify) sends its own payload, -payload can't be used with it")
+		}
+		if (Content.Mode == ethr.ContentRandom || Content.Mode == ethr.ContentFile) && SendMode != ethr.SendCopy {
+			return fmt.Errorf("%s payloads change with every message, zero-copy sending (-zc) can't be used with them", Content.Mode)
+		}
+		if Content.Mode == ethr.ContentFile && (Reverse || Bidirectional) {
+			return fmt.Errorf("file payloads only shape what the client sends, they can't be used with -r or -bidir")
+		}
+		for _, cc := range CongestionControl {
+			if cc == "" {
+				return fmt.Errorf("invalid argument, empty congestion control algorithm in \"-cc\"")
+			}
+		}
+		if NoKeepAlive && Protocol != ethr.HTTP && Protocol != ethr.HTTPS {
+			return fmt.Errorf("disabling keep-alive (-nka) is only supported for HTTP and HTTPS tests")
+		}
+		if (IgnoreCert || len(TLSVersions) > 0 || len(CipherSuites) > 0) && Protocol != ethr.HTTPS {
+			return fmt.Errorf("-ic, -tls and -ciphers are only supported for HTTPS tests")
 		}
 
 		switch Protocol {
 		case ethr.TCP:
 			switch TestType {
-			case ethr.TestTypeBandwidth, ethr.TestTypeConnectionsPerSecond, ethr.TestTypeLatency, ethr.TestTypePing, ethr.TestTypeTraceRoute, ethr.TestTypeMyTraceRoute:
+			case ethr.TestTypeBandwidth, ethr.TestTypeConnectionsPerSecond, ethr.TestTypeLatency, ethr.TestTypePing, ethr.TestTypeTraceRoute, ethr.TestTypeMyTraceRoute, ethr.TestTypeLatencyUnderLoad:
 				if BufferSize > 2*ui.GIGA {
 					return fmt.Errorf("maximum tcp buffer size is 2GB")
 				}
+			case ethr.TestTypeMaxConnections:
+			case ethr.TestTypeRequestResponse, ethr.TestTypeConnectRequestResponse:
+				if BufferSize > ui.GIGA || ResponseSize > ui.GIGA {
+					return fmt.Errorf("maximum request and response size is 1GB")
+				}
 			default:
 				return unsupportedTest()
 			}
 		case ethr.UDP:
 			switch TestType {
-			case ethr.TestTypeBandwidth, ethr.TestTypePacketsPerSecond:
+			case ethr.TestTypeBandwidth, ethr.TestTypePacketsPerSecond, ethr.TestTypeLatency, ethr.TestTypeTraceRoute, ethr.TestTypeMyTraceRoute:
 				if BufferSize > 64*ui.KILO {
 					return fmt.Errorf("maximum udp buffer is 64KB")
 				}
 			default:
 				return unsupportedTest()
 			}
-		//case ethr.ICMP:
-		//	switch TestType {
-		//	case ethr.TestTypePing, ethr.TestTypeTraceRoute, ethr.TestTypeMyTraceRoute:
-		//	default:
-		//		return unsupportedTest()
-		//	}
+		case ethr.HTTP, ethr.HTTPS:
+			switch TestType {
+			case ethr.TestTypeBandwidth, ethr.TestTypeConnectionsPerSecond, ethr.TestTypeLatency:
+				if BufferSize > ui.GIGA {
+					return fmt.Errorf("maximum http buffer size is 1GB")
+				}
+			default:
+				return unsupportedTest()
+			}
+		case ethr.ICMP:
+			switch TestType {
+			case ethr.TestTypePing, ethr.TestTypeTraceRoute, ethr.TestTypeMyTraceRoute, ethr.TestTypeMTU:
+			default:
+				return unsupportedTest()
+			}
 		default:
 			return unsupportedTest()
 		}
@@ -302,6 +681,16 @@ func validateClientArgs() error {
 	return nil
 }
 
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
 func unsupportedTest() error {
 	return fmt.Errorf("unsupported test/protocol: (%s/%s)", TestType, Protocol)
 }
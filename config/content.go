@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/ui"
+)
+
+// parseContent parses the payload of a test given as zeros, pattern, random or
+// file:<path>, the file is loaded into memory.
+func parseContent(s string) (ethr.Content, error) {
+	if !strings.HasPrefix(strings.ToLower(s), "file:") {
+		mode, err := ethr.ParseContentMode(s)
+		return ethr.Content{Mode: mode}, err
+	}
+	name := s[len("file:"):]
+	if name == "" {
+		return ethr.Content{}, fmt.Errorf("missing file in %q", s)
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return ethr.Content{}, fmt.Errorf("failed to read payload file: %w", err)
+	}
+	if len(data) == 0 {
+		return ethr.Content{}, fmt.Errorf("payload file %q is empty", name)
+	}
+	if uint64(len(data)) > ui.GIGA {
+		return ethr.Content{}, fmt.Errorf("payload file %q is larger than 1GB", name)
+	}
+	return ethr.FileContent(data), nil
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/ui"
+)
+
+// parseProfile parses traffic profiles given as
+//
+//	onoff:<period>:<percent on>
+//	ramp:<from rate>:<to rate>[:<duration>]
+//	steps:<step duration>:<rate>,<rate>,...
+//	poisson
+//
+// A ramp without a duration lasts the whole test.
+func parseProfile(s string, testDuration time.Duration) (ethr.TrafficProfile, error) {
+	if s == "" {
+		return ethr.TrafficProfile{}, nil
+	}
+	parts := strings.Split(s, ":")
+	switch strings.ToLower(parts[0]) {
+	case "onoff":
+		if len(parts) != 3 {
+			return ethr.TrafficProfile{}, fmt.Errorf("on/off profile must be onoff:<period>:<percent on>")
+		}
+		period, err := time.ParseDuration(parts[1])
+		if err != nil || period <= 0 {
+			return ethr.TrafficProfile{}, fmt.Errorf("invalid on/off period %q", parts[1])
+		}
+		percent, err := strconv.ParseFloat(parts[2], 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return ethr.TrafficProfile{}, fmt.Errorf("invalid on/off duty cycle %q, must be a percentage above 0", parts[2])
+		}
+		return ethr.TrafficProfile{Kind: ethr.ProfileOnOff, Period: period, DutyCycle: percent / 100}, nil
+	case "ramp":
+		if len(parts) != 3 && len(parts) != 4 {
+			return ethr.TrafficProfile{}, fmt.Errorf("ramp profile must be ramp:<from rate>:<to rate>[:<duration>]")
+		}
+		rates, err := parseRates(parts[1:3])
+		if err != nil {
+			return ethr.TrafficProfile{}, err
+		}
+		duration := testDuration
+		if len(parts) == 4 {
+			duration, err = time.ParseDuration(parts[3])
+			if err != nil {
+				return ethr.TrafficProfile{}, fmt.Errorf("invalid ramp duration %q", parts[3])
+			}
+		}
+		if duration <= 0 {
+			return ethr.TrafficProfile{}, fmt.Errorf("ramp profile of a test running forever needs a duration")
+		}
+		return ethr.TrafficProfile{Kind: ethr.ProfileRamp, Period: duration, Rates: rates}, nil
+	case "steps":
+		if len(parts) != 3 {
+			return ethr.TrafficProfile{}, fmt.Errorf("steps profile must be steps:<step duration>:<rate>,<rate>,...")
+		}
+		period, err := time.ParseDuration(parts[1])
+		if err != nil || period <= 0 {
+			return ethr.TrafficProfile{}, fmt.Errorf("invalid step duration %q", parts[1])
+		}
+		rates, err := parseRates(strings.Split(parts[2], ","))
+		if err != nil {
+			return ethr.TrafficProfile{}, err
+		}
+		return ethr.TrafficProfile{Kind: ethr.ProfileSteps, Period: period, Rates: rates}, nil
+	case "poisson":
+		if len(parts) != 1 {
+			return ethr.TrafficProfile{}, fmt.Errorf("poisson profile takes its rate from -b")
+		}
+		return ethr.TrafficProfile{Kind: ethr.ProfilePoisson}, nil
+	}
+	return ethr.TrafficProfile{}, fmt.Errorf("unknown traffic profile %q", parts[0])
+}
+
+// parseRates parses rates in bits/s like -b and returns them in bytes/s.
+func parseRates(raw []string) ([]uint64, error) {
+	rates := make([]uint64, 0, len(raw))
+	for _, r := range raw {
+		rate := ui.UnitToNumber(r) / 8
+		if rate == 0 {
+			return nil, fmt.Errorf("invalid rate %q", r)
+		}
+		rates = append(rates, rate)
+	}
+	return rates, nil
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/ui"
+)
+
+// parseSizes parses message size distributions given as
+//
+//	fixed:<size>
+//	uniform:<min size>:<max size>
+//	weighted:<size>=<weight>,<size>=<weight>,...
+//	csv:<file>
+//
+// Rows of the CSV file are <size>[,<weight>], rows without a weight count once
+// and rows of the same size add up, so a capture of message sizes can be used
+// as is. Distributions are limited to ethr.MaxSizes different sizes.
+func parseSizes(s string) (ethr.SizeDistribution, error) {
+	if s == "" {
+		return ethr.SizeDistribution{}, nil
+	}
+	// the file name of csv can have colons
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) != 2 {
+		return ethr.SizeDistribution{}, fmt.Errorf("missing sizes in %q", s)
+	}
+	d := ethr.SizeDistribution{Seed: time.Now().UnixNano()}
+	switch strings.ToLower(parts[0]) {
+	case "fixed":
+		size, err := parseSize(parts[1])
+		if err != nil {
+			return ethr.SizeDistribution{}, err
+		}
+		d.Kind = ethr.SizeFixed
+		d.Sizes = []uint32{size}
+	case "uniform":
+		bounds := strings.Split(parts[1], ":")
+		if len(bounds) != 2 {
+			return ethr.SizeDistribution{}, fmt.Errorf("uniform sizes must be uniform:<min size>:<max size>")
+		}
+		min, err := parseSize(bounds[0])
+		if err != nil {
+			return ethr.SizeDistribution{}, err
+		}
+		max, err := parseSize(bounds[1])
+		if err != nil {
+			return ethr.SizeDistribution{}, err
+		}
+		if min > max {
+			return ethr.SizeDistribution{}, fmt.Errorf("minimum size %q is above the maximum size %q", bounds[0], bounds[1])
+		}
+		d.Kind = ethr.SizeUniform
+		d.Sizes = []uint32{min, max}
+	case "weighted":
+		weights := make(map[uint32]uint64)
+		for _, entry := range strings.Split(parts[1], ",") {
+			pair := strings.Split(entry, "=")
+			if len(pair) != 2 {
+				return ethr.SizeDistribution{}, fmt.Errorf("weighted sizes must be weighted:<size>=<weight>,<size>=<weight>,...")
+			}
+			size, err := parseSize(pair[0])
+			if err != nil {
+				return ethr.SizeDistribution{}, err
+			}
+			weight, err := parseWeight(pair[1])
+			if err != nil {
+				return ethr.SizeDistribution{}, err
+			}
+			weights[size] += weight
+		}
+		d.Kind = ethr.SizeWeighted
+		d.Sizes, d.Weights = sortWeights(weights)
+	case "csv":
+		weights, err := readSizes(parts[1])
+		if err != nil {
+			return ethr.SizeDistribution{}, err
+		}
+		d.Kind = ethr.SizeEmpirical
+		d.Sizes, d.Weights = sortWeights(weights)
+	default:
+		return ethr.SizeDistribution{}, fmt.Errorf("unknown size distribution %q", parts[0])
+	}
+	if len(d.Sizes) > ethr.MaxSizes {
+		return ethr.SizeDistribution{}, fmt.Errorf("%d different sizes, at most %d are supported: round the sizes to fewer values", len(d.Sizes), ethr.MaxSizes)
+	}
+	total := uint64(0)
+	for _, w := range d.Weights {
+		total += w
+	}
+	if len(d.Weights) > 0 && total == 0 {
+		return ethr.SizeDistribution{}, fmt.Errorf("the weights of the sizes are all 0")
+	}
+	return d, nil
+}
+
+// readSizes reads the weight of each size from a CSV file, a first row that
+// isn't a size is taken as a header.
+func readSizes(name string) (map[uint32]uint64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sizes file: %w", err)
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+	weights := make(map[uint32]uint64)
+	for row := 1; ; row++ {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read sizes file: %w", err)
+		}
+		if len(record) > 2 {
+			return nil, fmt.Errorf("row %d of the sizes file must be <size>[,<weight>]", row)
+		}
+		size, err := parseSize(record[0])
+		if err != nil {
+			if row == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("row %d of the sizes file: %w", row, err)
+		}
+		weight := uint64(1)
+		if len(record) == 2 {
+			weight, err = parseWeight(record[1])
+			if err != nil {
+				return nil, fmt.Errorf("row %d of the sizes file: %w", row, err)
+			}
+		}
+		weights[size] += weight
+	}
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("no sizes in sizes file %q", name)
+	}
+	return weights, nil
+}
+
+func parseSize(s string) (uint32, error) {
+	size := ui.UnitToNumber(s)
+	if size == 0 || size > ui.GIGA {
+		return 0, fmt.Errorf("invalid size %q, must be between 1B and 1GB", s)
+	}
+	return uint32(size), nil
+}
+
+func parseWeight(s string) (uint64, error) {
+	weight, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid weight %q", s)
+	}
+	return weight, nil
+}
+
+// sortWeights returns the sizes in increasing order with their weights, so the
+// same distribution always picks the same sizes for the same seed.
+func sortWeights(weights map[uint32]uint64) ([]uint32, []uint64) {
+	sizes := make([]uint32, 0, len(weights))
+	for size := range weights {
+		sizes = append(sizes, size)
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		return sizes[i] < sizes[j]
+	})
+	ordered := make([]uint64, len(sizes))
+	for i, size := range sizes {
+		ordered[i] = weights[size]
+	}
+	return sizes, ordered
+}
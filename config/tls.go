@@ -0,0 +1,68 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+func parseTLSVersions(s string) ([]uint16, error) {
+	if s == "" {
+		return nil, nil
+	}
+	versions := make([]uint16, 0)
+	for _, v := range strings.Split(s, ",") {
+		version, err := parseTLSVersion(v)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
+
+func parseCipherSuites(s string) ([]uint16, error) {
+	if s == "" {
+		return nil, nil
+	}
+	suites := make([]uint16, 0)
+	for _, c := range strings.Split(s, ",") {
+		suite, err := parseCipherSuite(c)
+		if err != nil {
+			return nil, err
+		}
+		suites = append(suites, suite)
+	}
+	return suites, nil
+}
+
+// parseTLSVersion parses versions given as "1.0" to "1.3".
+func parseTLSVersion(s string) (uint16, error) {
+	switch strings.TrimPrefix(strings.ToUpper(strings.TrimSpace(s)), "TLS") {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, fmt.Errorf("unknown TLS version: %s", s)
+	}
+}
+
+// parseCipherSuite accepts the Go (IANA) name of a cipher suite, with or without
+// the "TLS_" prefix.
+func parseCipherSuite(s string) (uint16, error) {
+	name := strings.ToUpper(strings.TrimSpace(s))
+	if !strings.HasPrefix(name, "TLS_") {
+		name = "TLS_" + name
+	}
+	for _, suite := range tls.CipherSuites() {
+		if suite.Name == name {
+			return suite.ID, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown cipher suite: %s", s)
+}
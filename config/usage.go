@@ -16,6 +16,11 @@ func Usage() {
 	printFlagUsage("debug", "", "Enable debug information in logging output.")
 	printFlagUsage("4", "", "Use only IP v4 version")
 	printFlagUsage("6", "", "Use only IP v6 version")
+	printFlagUsage("batch", "<number>", "UDP datagrams sent or received per system call with sendmmsg and recvmmsg,",
+		"for UDP Bandwidth and Packets/s tests. Linux moves them in one call, other",
+		"platforms one at a time. The server batches what it receives, clients what they",
+		"send and receive, including the streams the server sends them. Max: 1024",
+		"Default: 1")
 
 	fmt.Println("\nMode: Server")
 	fmt.Println("================================================================================")
@@ -25,6 +30,7 @@ func Usage() {
 	printIPUsage()
 	printPortUsage()
 	printFlagUsage("ui", "", "Show output in text UI.")
+	printCertUsage()
 
 	fmt.Println("\nMode: Client")
 	fmt.Println("================================================================================")
@@ -40,7 +46,104 @@ func Usage() {
 	printThreadUsage()
 	printProtocolUsage()
 	printPortUsage()
-	printFlagUsage("r", "", "For Bandwidth tests, send data from server to client.")
+	printNoKeepAliveUsage()
+	printIgnoreCertUsage()
+	printTLSVersionsUsage()
+	printCipherSuitesUsage()
+	printFlagUsage("r", "", "For Bandwidth and UDP Packets/s tests, send data from server to client.",
+		"The server sends each UDP stream at 10Gbits/s at most.")
+	printFlagUsage("bidir", "", "For TCP and UDP Bandwidth and UDP Packets/s tests, send data in both directions at once.",
+		"Throughput, and loss for UDP, are reported for each direction.")
+	printFlagUsage("paris", "", "For UDP TraceRoute and MyTraceRoute tests, keep the 5-tuple and checksum",
+		"of probes constant so that ECMP load balancing doesn't change the path.")
+	printFlagUsage("flows", "<number>", "For UDP TraceRoute and MyTraceRoute tests, discover the paths of ECMP",
+		"load balancers with probes of <number> flows, each flow keeps its own 5-tuple.",
+		"Every router seen at each hop is reported with the flows reaching it.",
+		"Default: 1")
+	printFlagUsage("cc", "<algorithms>", "For TCP Bandwidth, Latency and Latency under load tests, comma separated",
+		"congestion control algorithms, e.g. \"bbr\" or \"bbr,cubic\". Streams use them round robin",
+		"and the server uses the same algorithm as the client on each stream. Linux only.",
+		"Default: <empty> - System default")
+	printFlagUsage("nagle", "", "For TCP Bandwidth, Latency and Latency under load tests, enable Nagle's",
+		"algorithm (TCP_NODELAY off) on both ends of each stream. Default: off")
+	printFlagUsage("sndbuf", "<size>", "For the same TCP tests, socket send buffer size of both ends (format: <num>[KB | MB]).",
+		"The sizes granted by the kernel are reported at the end of the test.",
+		"Default: <empty> - System default")
+	printFlagUsage("rcvbuf", "<size>", "For the same TCP tests, socket receive buffer size of both ends (format: <num>[KB | MB]).",
+		"Default: <empty> - System default")
+	printFlagUsage("mss", "<bytes>", "For the same TCP tests, maximum segment size (TCP_MAXSEG) of both ends.",
+		"The server sets it on accepted connections, it only limits what the server sends.",
+		"Default: <empty> - Negotiated by TCP")
+	printFlagUsage("linger", "<seconds>", "For the same TCP tests, SO_LINGER of both ends, 0 resets streams on close,",
+		"<0 closes them in the background. Closing gracefully leaves the client's local",
+		"ports in TIME_WAIT for a while.",
+		"Default: 0 on the client, the server keeps the system default")
+	printFlagUsage("zc", "<mode>", "For TCP Bandwidth tests, how the sender hands its buffer to the kernel, Linux only.",
+		"msg: MSG_ZEROCOPY, the kernel sends from the buffer instead of copying it",
+		"sendfile: sendfile from a memfd holding the buffer",
+		"The client reports the CPU time it spent per gigabit, to compare the modes.",
+		"Can't be used with -r, the server sends in reverse mode.",
+		"Default: <empty> - Copy the buffer with every write")
+	printFlagUsage("gso", "", "For UDP Bandwidth and Packets/s tests, send up to 64 datagrams per system call",
+		"as one buffer the kernel splits into datagrams (UDP GSO), the server sends its",
+		"streams the same way. Datagrams (-l) must fit the path MTU. Linux only.",
+		"Linux servers always let the kernel coalesce what they receive (UDP GRO),",
+		"datagrams and bytes are still counted as they were on the wire.",
+		"Default: off")
+	printFlagUsage("pace", "<mode>", "For TCP and UDP Bandwidth and UDP Packets/s tests limited with -b, how both",
+		"ends keep to the rate.",
+		"user: wait before every send until the token bucket holds it",
+		"kernel: set SO_MAX_PACING_RATE on each stream, Linux only. UDP streams need",
+		"the fq qdisc on the interface, e.g. tc qdisc replace dev eth0 root fq.",
+		"The server paces the UDP streams it sends in user space.",
+		"Default: user")
+	printFlagUsage("burst", "<size>", "For tests limited with -b, size of the token bucket (format: <num>[KB | MB]).",
+		"The bucket starts full, streams send up to <size> at once after being idle.",
+		"Default: <empty> - A millisecond of the rate, at least one buffer (-l)")
+	printFlagUsage("bshare", "", "For tests limited with -b, all streams share the rate and the burst instead",
+		"of each having its own. The server splits them evenly over the streams it sends.")
+	printFlagUsage("profile", "<profile>", "For Bandwidth and UDP Packets/s tests, change the rate over time. Rates are",
+		"given like -b, intervals show the rate aimed at in a Target column.",
+		"onoff:<period>:<percent>: send at -b for <percent> of every <period>",
+		"ramp:<from>:<to>[:<duration>]: go linearly from <from> to <to>, over the",
+		"whole test unless a <duration> is given",
+		"steps:<duration>:<rate>,<rate>,...: keep to each rate for <duration>",
+		"poisson: send at random times, averaging -b",
+		"UDP tests only shape what the client sends. Default: <empty> - Constant rate")
+	printFlagUsage("sizes", "<distribution>", "For TCP Bandwidth and Latency tests, sizes of the messages sent instead of -l,",
+		"the buffer holds the largest. Results are broken out by size at the end.",
+		"fixed:<size>: every message is <size>",
+		"uniform:<min>:<max>: any size from <min> to <max>",
+		"weighted:<size>=<weight>,...: each size as often as its weight",
+		"csv:<file>: rows of <size>[,<weight>], e.g. sizes captured from an application",
+		"Up to 1024 different sizes.",
+		"Latency tests keep the size of the reply the same as the request.",
+		"Default: <empty> - Every message is a buffer (-l)")
+	printFlagUsage("payload", "<payload>", "For TCP and UDP Bandwidth and Latency tests, the bytes sent.",
+		"zeros: all zeros, compresses the best",
+		"pattern: the bytes 0 to 255 over and over",
+		"random: cryptographically random, can't be compressed or deduplicated",
+		"file:<path>: the content of the file over and over, loaded into memory.",
+		"Only shapes what the client sends, can't be used with -r or -bidir.",
+		"random and file can't be used with -zc.",
+		"Default: pattern")
+	printFlagUsage("resp", "<size>", "For request/response tests (-t rr, crr), size of each response (format: <num>[KB | MB]),",
+		"requests are of the buffer size (-l). Max 1GB.",
+		"Default: <empty> - As large as the requests")
+	printFlagUsage("ramp", "<number>", "For max connections tests (-t mc), connections opened per second. Opening",
+		"continues at this rate when connections fail, the summary tells how many",
+		"were held at once, the time to connect at each level and where failures",
+		"started: file descriptors, ephemeral ports, socket memory, server refused or timeout.",
+		"Default: 1000")
+	printFlagUsage("maxconn", "<number>", "For max connections tests, stop opening connections once <number> are held.",
+		"Default: 0 - No limit")
+	printFlagUsage("active", "<interval>", "For max connections tests, exchange a byte with the server on every held",
+		"connection once per <interval>, connections failing to are dropped.",
+		"Default: 0 - Keep the connections idle")
+	printFlagUsage("verify", "", "For TCP Bandwidth tests, senders send a pseudo-random stream and receivers",
+		"check every byte of it. Corruption is reported with its offset in the stream,",
+		"the summary tells what was verified on each connection. In bidirectional tests",
+		"the server only logs what it verified. Can't be used with -zc.")
 	printTestType()
 	printToSUsage()
 	printWarmupUsage()
@@ -93,23 +196,29 @@ func printPortUsage() {
 }
 
 func printTestType() {
-	printFlagUsage("t", "<test>", "Test to run (\"b\", \"c\", \"p\", \"l\", \"cl\" or \"tr\")",
+	printFlagUsage("t", "<test>", "Test to run (\"b\", \"c\", \"p\", \"l\", \"cl\", \"tr\", \"mtu\", \"ll\", \"rr\", \"crr\" or \"mc\")",
 		"b: Bandwidth",
-		"c: Connections/s",
+		"c: Connections/s (Requests/s for HTTP & HTTPS)",
 		"p: Packets/s",
-		"l: Latency, Loss & Jitter",
+		"l: Latency, Loss & Jitter (TLS handshake latency for HTTPS)",
 		"pi: Ping Loss & Latency",
 		"tr: TraceRoute",
 		"mtr: MyTraceRoute with Loss & Latency",
+		"mtu: Path MTU (ICMP only)",
+		"ll: Latency under load, bufferbloat grade (TCP only)",
+		"rr: Request/response transactions/s and latency over persistent connections (TCP only)",
+		"crr: Request/response with a new connection per transaction (TCP only)",
+		"mc: Max concurrent connections held, ramping up (TCP only)",
 		"Default: b - Bandwidth measurement.")
 }
 
 func printExtTestType() {
-	printFlagUsage("t", "<test>", "Test to run (\"c\", \"cl\", or \"tr\")",
+	printFlagUsage("t", "<test>", "Test to run (\"c\", \"cl\", \"tr\" or \"mtu\")",
 		"c: Connections/s",
 		"pi: Ping Loss & Latency",
 		"tr: TraceRoute",
 		"mtr: MyTraceRoute with Loss & Latency",
+		"mtu: Path MTU (ICMP only)",
 		"Default: pi - Ping Loss & Latency.")
 }
 
@@ -138,7 +247,10 @@ func printBufLenUsage() {
 	printFlagUsage("l", "<length>",
 		"Length of buffer (in Bytes) to use (format: <num>[KB | MB | GB])",
 		"Only valid for Bandwidth tests. Max 1GB.",
-		"Default: 16KB")
+		"For HTTP, this is the size of each request or response body.",
+		"UDP datagrams of at least 24 bytes carry sequence numbers, the",
+		"server reports loss, reordering and jitter at the end of the test.",
+		"Default: 16KB, 1MB for HTTP")
 }
 
 func printProtocolUsage() {
@@ -157,7 +269,7 @@ func printIterationUsage() {
 	printFlagUsage("i", "<iterations>",
 		"Number of round trip iterations for each latency measurement.",
 		"Only valid for latency testing.",
-		"Default: 1000")
+		"Default: 1000, 10 handshakes per TLS profile for HTTPS")
 }
 
 func printNoConnStatUsage() {
@@ -167,10 +279,38 @@ func printNoConnStatUsage() {
 		"connections are used as specified by -n option for Bandwidth tests.")
 }
 
+func printNoKeepAliveUsage() {
+	printFlagUsage("nka", "",
+		"Disable HTTP keep-alive, a new connection is used for every request.",
+		"Only valid for HTTP and HTTPS tests.")
+}
+
+func printTLSVersionsUsage() {
+	printFlagUsage("tls", "<versions>",
+		"Comma separated TLS versions to test (\"1.0\", \"1.1\", \"1.2\" or \"1.3\").",
+		"Results are reported per TLS version and cipher suite.",
+		"Only valid for HTTPS tests. Default: 1.2,1.3")
+}
+
+func printCipherSuitesUsage() {
+	printFlagUsage("ciphers", "<names>",
+		"Comma separated cipher suites to test before TLS 1.3.",
+		"Example: ECDHE_ECDSA_WITH_AES_128_GCM_SHA256",
+		"Only valid for HTTPS tests. Default: All suites supported by the server")
+}
+
+func printCertUsage() {
+	printFlagUsage("cert", "<filename>",
+		"Certificate used for HTTPS tests, must be used with -key.",
+		"Default: <empty> - Self-signed certificate created at startup")
+	printFlagUsage("key", "<filename>", "Private key of the certificate given with -cert.")
+}
+
 func printIgnoreCertUsage() {
 	printFlagUsage("ic", "",
 		"Ignore Certificate is useful for HTTPS tests, for cases where a",
-		"middle box like a proxy is not able to supply a valid Ethr cert.")
+		"middle box like a proxy is not able to supply a valid Ethr cert.",
+		"Servers using their self-signed certificate need it too.")
 }
 
 func printWarmupUsage() {
@@ -185,7 +325,8 @@ func printToSUsage() {
 
 func printBwRateUsage() {
 	printFlagUsage("b", "<rate>",
-		"Transmit only Bits per second (format: <num>[K | M | G])",
+		"Transmit only Bits per second (format: <num>[K | M | G]), per stream",
+		"unless -bshare is used. A token bucket keeps the rate, see -burst.",
 		"Only valid for Bandwidth tests. Default: 0 - Unlimited",
 		"Examples: 100 (100bits/s), 1M (1Mbits/s).")
 }
@@ -0,0 +1,36 @@
+package ethr
+
+import "time"
+
+const (
+	backoffMin = 10 * time.Millisecond
+	backoffMax = time.Second
+)
+
+// Backoff spaces the retries of a failing operation, the delay doubles from
+// 10ms up to 1s with every failure until Reset.
+type Backoff struct {
+	delay time.Duration
+}
+
+// Wait sleeps before the next retry and returns false when done is closed first.
+func (b *Backoff) Wait(done <-chan struct{}) bool {
+	if b.delay < backoffMin {
+		b.delay = backoffMin
+	} else if b.delay *= 2; b.delay > backoffMax {
+		b.delay = backoffMax
+	}
+	timer := time.NewTimer(b.delay)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// Reset is called once the operation succeeded.
+func (b *Backoff) Reset() {
+	b.delay = 0
+}
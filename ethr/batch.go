@@ -0,0 +1,60 @@
+package ethr
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+// BatchConn moves UDP datagrams in batches, with recvmmsg and sendmmsg on Linux.
+// Elsewhere each system call still moves a single datagram.
+type BatchConn struct {
+	v4 *ipv4.PacketConn
+	v6 *ipv6.PacketConn
+}
+
+func NewBatchConn(conn net.PacketConn) BatchConn {
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok && addr.IP.To4() != nil {
+		return BatchConn{v4: ipv4.NewPacketConn(conn)}
+	}
+	return BatchConn{v6: ipv6.NewPacketConn(conn)}
+}
+
+// NewBatch returns count messages with a buffer of size bytes each.
+func NewBatch(count, size int) []ipv4.Message {
+	ms := make([]ipv4.Message, count)
+	for i := range ms {
+		ms[i].Buffers = [][]byte{make([]byte, size)}
+	}
+	return ms
+}
+
+// ReadBatch waits for at least one datagram and returns the number of messages
+// filled, N of each message is the length of its datagram.
+func (c BatchConn) ReadBatch(ms []ipv4.Message) (int, error) {
+	if c.v4 != nil {
+		return c.v4.ReadBatch(ms, 0)
+	}
+	return c.v6.ReadBatch(ms, 0)
+}
+
+// WriteBatch sends all of ms, a single sendmmsg may send fewer. The number of
+// messages sent is returned, also when it fails part way.
+func (c BatchConn) WriteBatch(ms []ipv4.Message) (int, error) {
+	sent := 0
+	for sent < len(ms) {
+		var n int
+		var err error
+		if c.v4 != nil {
+			n, err = c.v4.WriteBatch(ms[sent:], 0)
+		} else {
+			n, err = c.v6.WriteBatch(ms[sent:], 0)
+		}
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
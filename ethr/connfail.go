@@ -0,0 +1,61 @@
+package ethr
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// ConnectFailure is why a connection couldn't be opened or was lost.
+type ConnectFailure uint8
+
+const (
+	ConnectFailureOther ConnectFailure = iota
+	// the process or the system ran out of file descriptors (EMFILE, ENFILE)
+	ConnectFailureFileLimit
+	// no local address and port left to connect from (EADDRNOTAVAIL)
+	ConnectFailurePorts
+	// the kernel ran out of memory for sockets (ENOBUFS, ENOMEM)
+	ConnectFailureMemory
+	// the server refused or reset the connection
+	ConnectFailureRefused
+	// the server didn't answer in time, usually as its accept queue is full
+	ConnectFailureTimeout
+	ConnectFailureCount
+)
+
+func (f ConnectFailure) String() string {
+	switch f {
+	case ConnectFailureFileLimit:
+		return "file descriptors"
+	case ConnectFailurePorts:
+		return "ephemeral ports"
+	case ConnectFailureMemory:
+		return "socket memory"
+	case ConnectFailureRefused:
+		return "server refused"
+	case ConnectFailureTimeout:
+		return "timeout"
+	}
+	return "other"
+}
+
+// ClassifyConnectError tells why opening a connection failed.
+func ClassifyConnectError(err error) ConnectFailure {
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		if f, ok := connectFailures[errno]; ok {
+			return f
+		}
+	}
+	if errors.Is(err, io.EOF) {
+		// the server closed the connection before answering the handshake
+		return ConnectFailureRefused
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ConnectFailureTimeout
+	}
+	return ConnectFailureOther
+}
@@ -0,0 +1,16 @@
+// +build linux
+
+package ethr
+
+import "syscall"
+
+var connectFailures = map[syscall.Errno]ConnectFailure{
+	syscall.EMFILE:        ConnectFailureFileLimit,
+	syscall.ENFILE:        ConnectFailureFileLimit,
+	syscall.EADDRNOTAVAIL: ConnectFailurePorts,
+	syscall.ENOBUFS:       ConnectFailureMemory,
+	syscall.ENOMEM:        ConnectFailureMemory,
+	syscall.ECONNREFUSED:  ConnectFailureRefused,
+	syscall.ECONNRESET:    ConnectFailureRefused,
+	syscall.ETIMEDOUT:     ConnectFailureTimeout,
+}
@@ -0,0 +1,18 @@
+// +build windows
+
+package ethr
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+var connectFailures = map[syscall.Errno]ConnectFailure{
+	windows.WSAEMFILE:        ConnectFailureFileLimit,
+	windows.WSAEADDRNOTAVAIL: ConnectFailurePorts,
+	windows.WSAENOBUFS:       ConnectFailureMemory,
+	windows.WSAECONNREFUSED:  ConnectFailureRefused,
+	windows.WSAECONNRESET:    ConnectFailureRefused,
+	windows.WSAETIMEDOUT:     ConnectFailureTimeout,
+}
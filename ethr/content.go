@@ -0,0 +1,126 @@
+package ethr
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"strings"
+)
+
+// ContentMode is what the bytes sent by bandwidth and latency tests are.
+type ContentMode uint8
+
+const (
+	// ContentPattern repeats the bytes 0 to 255.
+	ContentPattern ContentMode = iota
+	// ContentZeros sends zeros, the best case for compression.
+	ContentZeros
+	// ContentRandom sends a stream of AES-CTR keyed from crypto/rand, nothing
+	// along the path can compress or deduplicate it.
+	ContentRandom
+	// ContentFile sends the content of a file over and over.
+	ContentFile
+)
+
+func (m ContentMode) String() string {
+	switch m {
+	case ContentZeros:
+		return "zeros"
+	case ContentRandom:
+		return "random"
+	case ContentFile:
+		return "file"
+	}
+	return "pattern"
+}
+
+// ParseContentMode parses the modes without a file, see FileContent.
+func ParseContentMode(s string) (ContentMode, error) {
+	switch strings.ToLower(s) {
+	case "", "pattern":
+		return ContentPattern, nil
+	case "zeros":
+		return ContentZeros, nil
+	case "random":
+		return ContentRandom, nil
+	}
+	return ContentPattern, fmt.Errorf("invalid payload %q", s)
+}
+
+// Content is the payload of a test. The content of a file stays with the
+// client, it isn't sent to the server in the handshake.
+type Content struct {
+	Mode ContentMode
+	file []byte
+}
+
+// FileContent returns the content of a test sending data over and over.
+func FileContent(data []byte) Content {
+	return Content{Mode: ContentFile, file: data}
+}
+
+// NewFiller returns what fills the buffers of one sender. Without the content
+// of the file, e.g. on the server, files fall back to the pattern.
+func (c Content) NewFiller() (*ContentFiller, error) {
+	f := &ContentFiller{mode: c.Mode, file: c.file}
+	if f.mode == ContentFile && len(f.file) == 0 {
+		f.mode = ContentPattern
+	}
+	if f.mode == ContentRandom {
+		key := make([]byte, 32)
+		iv := make([]byte, aes.BlockSize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to read a random key: %w", err)
+		}
+		if _, err := rand.Read(iv); err != nil {
+			return nil, fmt.Errorf("failed to read a random IV: %w", err)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		f.stream = cipher.NewCTR(block, iv)
+	}
+	return f, nil
+}
+
+// ContentFiller writes the content of a test to the buffers of a sender.
+type ContentFiller struct {
+	mode   ContentMode
+	file   []byte
+	offset int
+	stream cipher.Stream
+}
+
+// Static tells if every message has the same content, senders fill their
+// buffer once then.
+func (f *ContentFiller) Static() bool {
+	return f.mode == ContentPattern || f.mode == ContentZeros
+}
+
+// Fill writes the next len(b) bytes of the content to b, a random stream never
+// repeats and a file continues where the last message stopped.
+func (f *ContentFiller) Fill(b []byte) {
+	switch f.mode {
+	case ContentPattern:
+		for i := range b {
+			b[i] = byte(i)
+		}
+	case ContentZeros:
+		for i := range b {
+			b[i] = 0
+		}
+	case ContentRandom:
+		for i := range b {
+			b[i] = 0
+		}
+		f.stream.XORKeyStream(b, b)
+	case ContentFile:
+		for len(b) > 0 {
+			n := copy(b, f.file[f.offset:])
+			b = b[n:]
+			f.offset = (f.offset + n) % len(f.file)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package ethr
+
+import "encoding/binary"
+
+// Datagrams sent by UDP tests start with a header identifying the test traffic,
+// datagrams without it are only counted by the server.
+const (
+	datagramMagic     = 0x45544852 // "ETHR"
+	DatagramHeaderLen = 24
+)
+
+type DatagramType uint8
+
+const (
+	DatagramUnknown DatagramType = iota
+	DatagramEcho
+	DatagramData
+	DatagramDone
+	DatagramReport
+	DatagramStart
+	DatagramCookie
+)
+
+// StreamReportLen is the length of a report datagram, the header is followed by
+// the statistics of the stream.
+const StreamReportLen = DatagramHeaderLen + 40
+
+// DatagramHeader is encoded as magic(4) type(1) reserved(3) sequence(8) and
+// send time(8), all big endian.
+type DatagramHeader struct {
+	Type     DatagramType
+	Sequence uint64
+	SendTime int64 // unix nanoseconds
+}
+
+// Encode writes the header to the start of b, which must be at least
+// DatagramHeaderLen bytes long.
+func (h DatagramHeader) Encode(b []byte) {
+	binary.BigEndian.PutUint32(b[0:], datagramMagic)
+	b[4] = byte(h.Type)
+	b[5], b[6], b[7] = 0, 0, 0
+	binary.BigEndian.PutUint64(b[8:], h.Sequence)
+	binary.BigEndian.PutUint64(b[16:], uint64(h.SendTime))
+}
+
+// DecodeDatagramHeader returns false when b does not start with a header.
+func DecodeDatagramHeader(b []byte) (DatagramHeader, bool) {
+	if len(b) < DatagramHeaderLen || binary.BigEndian.Uint32(b) != datagramMagic {
+		return DatagramHeader{}, false
+	}
+	return DatagramHeader{
+		Type:     DatagramType(b[4]),
+		Sequence: binary.BigEndian.Uint64(b[8:]),
+		SendTime: int64(binary.BigEndian.Uint64(b[16:])),
+	}, true
+}
+
+// StreamStartLen is the length of a start datagram, the header is followed by the
+// parameters of the stream the server is asked to send. Start datagrams of older
+// clients end before the batch size, the GSO flag, the pacing mode, the burst or
+// the content.
+const (
+	StreamStartLen    = DatagramHeaderLen + 23
+	streamStartMinLen = DatagramHeaderLen + 12
+)
+
+// StreamStart asks the server to send a stream to the address it came from, for
+// reverse and bidirectional UDP tests. It is repeated by the client to keep the
+// stream going. The server answers a start without the cookie of the address
+// with a DatagramCookie carrying it, the cookie goes in the sequence of the header.
+type StreamStart struct {
+	Cookie     uint64
+	BufferSize uint32
+	BwRate     uint64 // bytes/s, 0 is unlimited
+	BatchSize  uint32 // datagrams per sendmmsg, 0 and 1 send one at a time
+	GSO        bool   // send segmented by the kernel
+	Pacing     PaceMode
+	Burst      uint32 // bytes, 0 picks a default
+	Content    ContentMode
+}
+
+// Encode writes the start datagram to b, which must be at least StreamStartLen
+// bytes long.
+func (s StreamStart) Encode(b []byte) {
+	DatagramHeader{Type: DatagramStart, Sequence: s.Cookie}.Encode(b)
+	binary.BigEndian.PutUint32(b[24:], s.BufferSize)
+	binary.BigEndian.PutUint64(b[28:], s.BwRate)
+	binary.BigEndian.PutUint32(b[36:], s.BatchSize)
+	b[40] = 0
+	if s.GSO {
+		b[40] = 1
+	}
+	b[41] = byte(s.Pacing)
+	binary.BigEndian.PutUint32(b[42:], s.Burst)
+	b[46] = byte(s.Content)
+}
+
+// DecodeStreamStart returns false when b is not a start datagram.
+func DecodeStreamStart(b []byte) (StreamStart, bool) {
+	header, ok := DecodeDatagramHeader(b)
+	if !ok || header.Type != DatagramStart || len(b) < streamStartMinLen {
+		return StreamStart{}, false
+	}
+	start := StreamStart{
+		Cookie:     header.Sequence,
+		BufferSize: binary.BigEndian.Uint32(b[24:]),
+		BwRate:     binary.BigEndian.Uint64(b[28:]),
+	}
+	if len(b) >= DatagramHeaderLen+16 {
+		start.BatchSize = binary.BigEndian.Uint32(b[36:])
+	}
+	if len(b) >= DatagramHeaderLen+17 {
+		start.GSO = b[40] != 0
+	}
+	if len(b) >= DatagramHeaderLen+18 {
+		start.Pacing = PaceMode(b[41])
+	}
+	if len(b) >= DatagramHeaderLen+22 {
+		start.Burst = binary.BigEndian.Uint32(b[42:])
+	}
+	if len(b) >= StreamStartLen {
+		start.Content = ContentMode(b[46])
+	}
+	return start, true
+}
+
+// StreamReport is sent by the server in response to a DatagramDone whose sequence
+// is the number of datagrams sent by the client. When the server was sending a
+// stream as well, it also answers with a DatagramDone carrying its own count.
+type StreamReport struct {
+	Sent       uint64
+	Received   uint64
+	Lost       uint64
+	OutOfOrder uint64
+	Duplicates uint64
+	Jitter     int64 // nanoseconds, RFC 3550 interarrival jitter
+}
+
+// Encode writes the report datagram to b, which must be at least
+// StreamReportLen bytes long.
+func (r StreamReport) Encode(b []byte) {
+	DatagramHeader{Type: DatagramReport, Sequence: r.Sent}.Encode(b)
+	binary.BigEndian.PutUint64(b[24:], r.Received)
+	binary.BigEndian.PutUint64(b[32:], r.Lost)
+	binary.BigEndian.PutUint64(b[40:], r.OutOfOrder)
+	binary.BigEndian.PutUint64(b[48:], r.Duplicates)
+	binary.BigEndian.PutUint64(b[56:], uint64(r.Jitter))
+}
+
+// DecodeStreamReport returns false when b is not a report datagram.
+func DecodeStreamReport(b []byte) (StreamReport, bool) {
+	header, ok := DecodeDatagramHeader(b)
+	if !ok || header.Type != DatagramReport || len(b) < StreamReportLen {
+		return StreamReport{}, false
+	}
+	return StreamReport{
+		Sent:       header.Sequence,
+		Received:   binary.BigEndian.Uint64(b[24:]),
+		Lost:       binary.BigEndian.Uint64(b[32:]),
+		OutOfOrder: binary.BigEndian.Uint64(b[40:]),
+		Duplicates: binary.BigEndian.Uint64(b[48:]),
+		Jitter:     int64(binary.BigEndian.Uint64(b[56:])),
+	}, true
+}
@@ -0,0 +1,20 @@
+package ethr
+
+// Direction of the traffic in a bandwidth test, seen from the client.
+type Direction uint8
+
+const (
+	Upstream   Direction = iota // client to server
+	Downstream                  // server to client
+)
+
+func (d Direction) String() string {
+	switch d {
+	case Upstream:
+		return "Up"
+	case Downstream:
+		return "Down"
+	default:
+		return "Unknown"
+	}
+}
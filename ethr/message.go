@@ -6,15 +6,17 @@ const (
 	Inv MsgType = iota
 	Syn
 	Ack
+	Integrity
 )
 
 type MsgVer uint32
 
 type Msg struct {
-	Version MsgVer
-	Type    MsgType
-	Syn     *MsgSyn
-	Ack     *MsgAck
+	Version   MsgVer
+	Type      MsgType
+	Syn       *MsgSyn
+	Ack       *MsgAck
+	Integrity *MsgIntegrity
 }
 
 type MsgSyn struct {
@@ -22,5 +24,16 @@ type MsgSyn struct {
 	ClientParam ClientParams
 }
 
+// MsgAck accepts a stream, Error is why the server refused it instead and
+// StreamOptions the socket options the server end of the stream was granted.
 type MsgAck struct {
+	Error         string
+	StreamOptions StreamOptions
+}
+
+// MsgIntegrity is what the server verified of a stream the client sent.
+type MsgIntegrity struct {
+	Verified        uint64
+	Corrupted       uint64
+	FirstCorruption uint64
 }
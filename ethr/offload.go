@@ -0,0 +1,24 @@
+package ethr
+
+// UDPMaxSegments is the most datagrams the kernel splits a UDP GSO send into,
+// MaxSegmentedBytes keeps all of them within a single 64KB IPv6 packet.
+const (
+	UDPMaxSegments    = 64
+	MaxSegmentedBytes = 65535 - 40 - 8
+)
+
+// SegmentOOBLen is enough space for the control message of UDP GSO and GRO.
+const SegmentOOBLen = 64
+
+// AppendSegments appends the datagrams of b, coalesced by UDP GRO into segments
+// of size bytes, to dst. The last one can be shorter, size 0 appends b whole.
+func AppendSegments(dst [][]byte, b []byte, size int) [][]byte {
+	if size <= 0 || size >= len(b) {
+		return append(dst, b)
+	}
+	for len(b) > size {
+		dst = append(dst, b[:size])
+		b = b[size:]
+	}
+	return append(dst, b)
+}
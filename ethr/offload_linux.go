@@ -0,0 +1,52 @@
+// +build linux
+
+package ethr
+
+import (
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// not exported by the pinned golang.org/x/sys
+const (
+	solUDP     = 17
+	udpSegment = 103
+	udpGRO     = 104
+)
+
+// EnableUDPGRO lets the kernel coalesce datagrams of a flow received on conn,
+// the segment size is reported with every read, see GROSegmentSize.
+func EnableUDPGRO(conn interface{}) error {
+	return ControlSocket(conn, func(fd uintptr) error {
+		return syscall.SetsockoptInt(int(fd), solUDP, udpGRO, 1)
+	})
+}
+
+// SegmentOOB returns the control message splitting a send into datagrams of
+// size bytes with UDP GSO.
+func SegmentOOB(size int) []byte {
+	oob := make([]byte, unix.CmsgSpace(2))
+	h := (*unix.Cmsghdr)(unsafe.Pointer(&oob[0]))
+	h.Level = solUDP
+	h.Type = udpSegment
+	h.SetLen(unix.CmsgLen(2))
+	*(*uint16)(unsafe.Pointer(&oob[unix.CmsgLen(0)])) = uint16(size)
+	return oob
+}
+
+// GROSegmentSize returns the size of the datagrams coalesced in a read, 0 when
+// the read holds a single datagram.
+func GROSegmentSize(oob []byte) int {
+	msgs, err := unix.ParseSocketControlMessage(oob)
+	if err != nil {
+		return 0
+	}
+	for _, m := range msgs {
+		if m.Header.Level == solUDP && m.Header.Type == udpGRO && len(m.Data) >= 4 {
+			return int(*(*int32)(unsafe.Pointer(&m.Data[0])))
+		}
+	}
+	return 0
+}
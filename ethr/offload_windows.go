@@ -0,0 +1,15 @@
+// +build windows
+
+package ethr
+
+func EnableUDPGRO(conn interface{}) error {
+	return ErrNotSupported
+}
+
+func SegmentOOB(size int) []byte {
+	return nil
+}
+
+func GROSegmentSize(oob []byte) int {
+	return 0
+}
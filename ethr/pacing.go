@@ -0,0 +1,34 @@
+package ethr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PaceMode is how bandwidth tests limited with -b keep to their rate.
+type PaceMode uint8
+
+const (
+	// PaceUser waits before every send until a token bucket holds its bytes.
+	PaceUser PaceMode = iota
+	// PaceKernel sets SO_MAX_PACING_RATE on the socket and lets the kernel space
+	// its packets. UDP needs the fq qdisc on the interface. Linux only.
+	PaceKernel
+)
+
+func (m PaceMode) String() string {
+	if m == PaceKernel {
+		return "kernel"
+	}
+	return "user"
+}
+
+func ParsePaceMode(s string) (PaceMode, error) {
+	switch strings.ToLower(s) {
+	case "", "user":
+		return PaceUser, nil
+	case "kernel":
+		return PaceKernel, nil
+	}
+	return PaceUser, fmt.Errorf("invalid pacing mode %q", s)
+}
@@ -0,0 +1,22 @@
+// +build linux
+
+package ethr
+
+import (
+	"fmt"
+	"math"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// SetMaxPacingRate limits the socket of conn to rate bytes/s.
+func SetMaxPacingRate(conn interface{}, rate uint64) error {
+	// older kernels only take 32 bits
+	if rate >= math.MaxUint32 {
+		return fmt.Errorf("rate of %d bytes/s is too high for kernel pacing", rate)
+	}
+	return ControlSocket(conn, func(fd uintptr) error {
+		return syscall.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_MAX_PACING_RATE, int(rate))
+	})
+}
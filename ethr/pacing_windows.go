@@ -0,0 +1,7 @@
+// +build windows
+
+package ethr
+
+func SetMaxPacingRate(conn interface{}, rate uint64) error {
+	return ErrNotSupported
+}
@@ -12,6 +12,99 @@ type ClientParams struct {
 	WarmupCount uint32
 	BwRate      uint64
 	ToS         uint8
+	NoKeepAlive bool
+
+	// Bandwidth tests sending in both directions at once
+	Bidirectional bool
+
+	// TCP only, the streams use the congestion control algorithms round robin
+	// and the server uses the same algorithm as the client on each connection
+	CongestionControl []string
+
+	// TCP only, socket options set on both ends of each stream, zero values keep
+	// the defaults. Linger is in seconds, <0 closes in the background, without
+	// SetLinger the client resets its streams on close.
+	Nagle         bool
+	SendBuffer    uint32
+	ReceiveBuffer uint32
+	MSS           uint32
+	SetLinger     bool
+	Linger        int
+
+	// TCP bandwidth only, how the client and server hand their buffer to the kernel
+	SendMode SendMode
+
+	// UDP bandwidth and packets/s only, datagrams moved per sendmmsg and recvmmsg
+	// call on both ends, 0 and 1 move them one at a time
+	BatchSize uint32
+	// UDP bandwidth and packets/s only, datagrams are sent as one buffer split by
+	// the kernel (UDP GSO) and received coalesced (UDP GRO). Linux only.
+	GSO bool
+
+	// Bandwidth and UDP packets/s tests only, BwRate is kept by a token bucket of
+	// Burst bytes, 0 picks a default. With SharedRate BwRate and Burst are shared by all streams,
+	// see ForStream. TCP and UDP can leave the pacing to the kernel instead.
+	Burst      uint32
+	SharedRate bool
+	Pacing     PaceMode
+	// Bandwidth and UDP packets/s tests only, how the rate changes over time.
+	// UDP only shapes what the client sends.
+	Profile TrafficProfile
+	// TCP bandwidth and latency only, the sizes of the messages sent, results
+	// are broken out by size. Each stream picks its own sequence of sizes.
+	Sizes SizeDistribution
+	// TCP and UDP bandwidth and latency only, the bytes sent. Servers send the
+	// pattern for files, see Content.
+	Content Content
+
+	// TCP bandwidth only, senders send the pseudo-random stream of the seed and
+	// receivers verify every byte of it. Each stream has its own seed.
+	Verify     bool
+	VerifySeed int64
+
+	// TCP request/response only, the server answers every request of BufferSize
+	// bytes with ResponseSize bytes
+	ResponseSize uint32
+
+	// TCP max connections only, connections opened per second, the most held at
+	// once, 0 for no limit, and how often each one exchanges a byte with the
+	// server, 0 keeps them idle
+	RampRate       uint32
+	MaxConnections uint32
+	KeepActive     time.Duration
+
+	// UDP traceroute only, probes keep the same 5-tuple and checksum on all hops
+	// and more than one flow discovers the paths of load balancers
+	Paris bool
+	Flows uint32
+
+	// HTTPS only, empty selects every version or cipher suite supported by Go
+	TLSVersions  []uint16
+	CipherSuites []uint16
+	IgnoreCert   bool
+}
+
+// ForStream returns the params of one stream of the test, with only the
+// congestion control algorithm of the stream, its share of a shared rate and
+// its own seeds of message sizes and payload.
+func (p ClientParams) ForStream(stream int) ClientParams {
+	if len(p.CongestionControl) > 0 {
+		p.CongestionControl = []string{p.CongestionControl[stream%len(p.CongestionControl)]}
+	}
+	if p.SharedRate && p.NumThreads > 1 {
+		p.BwRate = ShareRate(p.BwRate, stream, int(p.NumThreads))
+		p.Burst /= p.NumThreads
+		p.Profile = p.Profile.Share(stream, int(p.NumThreads))
+		p.SharedRate = false
+	}
+	p.Sizes.Seed += int64(stream)
+	p.VerifySeed += int64(stream)
+	return p
+}
+
+// HasStreamOptions tells if the test changes the socket options of its streams.
+func (p ClientParams) HasStreamOptions() bool {
+	return len(p.CongestionControl) > 0 || p.Nagle || p.SendBuffer > 0 || p.ReceiveBuffer > 0 || p.MSS > 0 || p.SetLinger
 }
 
 type ServerParams struct {
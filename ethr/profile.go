@@ -0,0 +1,101 @@
+package ethr
+
+import "time"
+
+// ProfileKind is the shape of the rate of a bandwidth test over time.
+type ProfileKind uint8
+
+const (
+	// ProfileConstant keeps to BwRate all along.
+	ProfileConstant ProfileKind = iota
+	// ProfileOnOff sends at BwRate for DutyCycle of every Period and nothing
+	// for the rest of it.
+	ProfileOnOff
+	// ProfileRamp goes linearly from Rates[0] to Rates[1] over Period and then
+	// keeps to Rates[1].
+	ProfileRamp
+	// ProfileSteps keeps to each of Rates for Period, the last one until the end.
+	ProfileSteps
+	// ProfilePoisson sends at random times, the gaps between sends are
+	// exponentially distributed to average BwRate.
+	ProfilePoisson
+)
+
+func (k ProfileKind) String() string {
+	switch k {
+	case ProfileOnOff:
+		return "onoff"
+	case ProfileRamp:
+		return "ramp"
+	case ProfileSteps:
+		return "steps"
+	case ProfilePoisson:
+		return "poisson"
+	}
+	return "constant"
+}
+
+// TrafficProfile makes the rate of a bandwidth test change over time, rates are
+// in bytes/s.
+type TrafficProfile struct {
+	Kind      ProfileKind
+	Period    time.Duration
+	DutyCycle float64
+	Rates     []uint64
+}
+
+// RateAt returns the rate elapsed into the test, rate is the BwRate of the test.
+func (p TrafficProfile) RateAt(elapsed time.Duration, rate uint64) uint64 {
+	switch p.Kind {
+	case ProfileOnOff:
+		if p.Period <= 0 {
+			return rate
+		}
+		if float64(elapsed%p.Period) >= p.DutyCycle*float64(p.Period) {
+			return 0
+		}
+		return rate
+	case ProfileRamp:
+		if len(p.Rates) < 2 {
+			return rate
+		}
+		if p.Period <= 0 || elapsed >= p.Period {
+			return p.Rates[1]
+		}
+		from, to := float64(p.Rates[0]), float64(p.Rates[1])
+		return uint64(from + (to-from)*float64(elapsed)/float64(p.Period))
+	case ProfileSteps:
+		if len(p.Rates) == 0 {
+			return rate
+		}
+		step := len(p.Rates) - 1
+		if p.Period > 0 && int64(elapsed/p.Period) < int64(step) {
+			step = int(elapsed / p.Period)
+		}
+		return p.Rates[step]
+	}
+	return rate
+}
+
+// Share returns the profile of one of threads streams sharing it.
+func (p TrafficProfile) Share(stream, threads int) TrafficProfile {
+	rates := make([]uint64, len(p.Rates))
+	for i, r := range p.Rates {
+		rates[i] = ShareRate(r, stream, threads)
+	}
+	p.Rates = rates
+	return p
+}
+
+// ShareRate splits rate evenly over threads streams and returns the share of
+// stream, which is never 0 for a rate that isn't.
+func ShareRate(rate uint64, stream, threads int) uint64 {
+	if threads <= 1 || rate == 0 {
+		return rate
+	}
+	share := rate / uint64(threads)
+	if uint64(stream%threads) < rate%uint64(threads) || share == 0 {
+		share++
+	}
+	return share
+}
@@ -0,0 +1,80 @@
+package ethr
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// SendMode is how TCP bandwidth tests hand their buffer to the kernel.
+type SendMode uint8
+
+const (
+	// SendCopy writes the buffer, the kernel copies it for every write.
+	SendCopy SendMode = iota
+	// SendMsgZeroCopy sends with MSG_ZEROCOPY, the kernel pins the buffer until
+	// the data is acked instead of copying it. Linux only.
+	SendMsgZeroCopy
+	// SendFile sends the buffer from a memfd with sendfile. Linux only.
+	SendFile
+)
+
+func (m SendMode) String() string {
+	switch m {
+	case SendMsgZeroCopy:
+		return "msg_zerocopy"
+	case SendFile:
+		return "sendfile"
+	}
+	return "copy"
+}
+
+func ParseSendMode(s string) (SendMode, error) {
+	switch strings.ToLower(s) {
+	case "", "copy":
+		return SendCopy, nil
+	case "msg", "msg_zerocopy":
+		return SendMsgZeroCopy, nil
+	case "sendfile":
+		return SendFile, nil
+	}
+	return SendCopy, fmt.Errorf("invalid send mode %q", s)
+}
+
+// SendStats counts the MSG_ZEROCOPY sends the kernel completed, Copied are the
+// ones it still had to copy, e.g. on loopback. Updated atomically.
+type SendStats struct {
+	ZeroCopied uint64
+	Copied     uint64
+}
+
+// StreamSender sends the first n bytes of the same buffer on a TCP stream over
+// and over.
+type StreamSender interface {
+	Send(n int) (int, error)
+	Close() error
+}
+
+// NewStreamSender returns a sender of buff in the given mode, stats is optional.
+func NewStreamSender(conn net.Conn, buff []byte, mode SendMode, stats *SendStats) (StreamSender, error) {
+	switch mode {
+	case SendMsgZeroCopy:
+		return newZeroCopySender(conn, buff, stats)
+	case SendFile:
+		return newFileSender(conn, buff)
+	}
+	return copySender{conn: conn, buff: buff}, nil
+}
+
+type copySender struct {
+	conn net.Conn
+	buff []byte
+}
+
+func (s copySender) Send(n int) (int, error) {
+	return s.conn.Write(s.buff[:n])
+}
+
+func (s copySender) Close() error {
+	return nil
+}
@@ -0,0 +1,13 @@
+// +build darwin
+
+package ethr
+
+import "net"
+
+func newZeroCopySender(conn net.Conn, buff []byte, stats *SendStats) (StreamSender, error) {
+	return nil, ErrNotSupported
+}
+
+func newFileSender(conn net.Conn, buff []byte) (StreamSender, error) {
+	return nil, ErrNotSupported
+}
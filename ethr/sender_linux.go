@@ -0,0 +1,180 @@
+// +build linux
+
+package ethr
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// zeroCopySender reaps the completions of its sends once a few are pending, the
+// kernel stops accepting MSG_ZEROCOPY sends when too many completions queue up.
+type zeroCopySender struct {
+	rc        syscall.RawConn
+	buff      []byte
+	stats     *SendStats
+	sent      uint32
+	completed uint32
+	data      []byte
+	oob       []byte
+}
+
+const (
+	zeroCopyPendingSends = 8
+	zeroCopyCloseTimeout = 500 * time.Millisecond
+)
+
+func newZeroCopySender(conn net.Conn, buff []byte, stats *SendStats) (StreamSender, error) {
+	rc, err := rawConn(conn)
+	if err != nil {
+		return nil, err
+	}
+	var optErr error
+	err = rc.Control(func(fd uintptr) {
+		optErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_ZEROCOPY, 1)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to access socket: %w", err)
+	}
+	if optErr != nil {
+		return nil, fmt.Errorf("failed to enable MSG_ZEROCOPY: %w", optErr)
+	}
+	if stats == nil {
+		stats = &SendStats{}
+	}
+	return &zeroCopySender{
+		rc:    rc,
+		buff:  buff,
+		stats: stats,
+		data:  make([]byte, 1),
+		oob:   make([]byte, 256),
+	}, nil
+}
+
+func (s *zeroCopySender) Send(n int) (int, error) {
+	var written int
+	var sendErr error
+	for {
+		err := s.rc.Write(func(fd uintptr) bool {
+			written, sendErr = unix.SendmsgN(int(fd), s.buff[:n], nil, nil, unix.MSG_ZEROCOPY)
+			return sendErr != unix.EAGAIN
+		})
+		if err != nil {
+			return 0, err
+		}
+		if sendErr != unix.ENOBUFS {
+			break
+		}
+		// the completions queued up faster than they were reaped
+		if s.reap() == 0 {
+			time.Sleep(50 * time.Microsecond)
+		}
+	}
+	if sendErr != nil {
+		return 0, sendErr
+	}
+	s.sent++
+	if s.sent-s.completed >= zeroCopyPendingSends {
+		s.reap()
+	}
+	return written, nil
+}
+
+// reap reads the completions off the socket's error queue without waiting, each
+// one covers a range of sends.
+func (s *zeroCopySender) reap() uint32 {
+	reaped := uint32(0)
+	_ = s.rc.Control(func(fd uintptr) {
+		for {
+			_, oobn, _, _, err := unix.Recvmsg(int(fd), s.data, s.oob, unix.MSG_ERRQUEUE|unix.MSG_DONTWAIT)
+			if err != nil {
+				return
+			}
+			msgs, err := unix.ParseSocketControlMessage(s.oob[:oobn])
+			if err != nil {
+				return
+			}
+			for _, m := range msgs {
+				if len(m.Data) < int(unsafe.Sizeof(unix.SockExtendedErr{})) {
+					continue
+				}
+				e := (*unix.SockExtendedErr)(unsafe.Pointer(&m.Data[0]))
+				if e.Origin != unix.SO_EE_ORIGIN_ZEROCOPY {
+					continue
+				}
+				count := e.Data - e.Info + 1
+				reaped += count
+				if e.Code&unix.SO_EE_CODE_ZEROCOPY_COPIED != 0 {
+					atomic.AddUint64(&s.stats.Copied, uint64(count))
+				} else {
+					atomic.AddUint64(&s.stats.ZeroCopied, uint64(count))
+				}
+			}
+		}
+	})
+	s.completed += reaped
+	return reaped
+}
+
+// Close waits for the completions of the last sends, for a while only as they
+// come once the peer acknowledged the data.
+func (s *zeroCopySender) Close() error {
+	deadline := time.Now().Add(zeroCopyCloseTimeout)
+	for s.completed < s.sent && time.Now().Before(deadline) {
+		if s.reap() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	return nil
+}
+
+// fileSender sends from a memfd holding the buffer, the kernel references the
+// file's pages instead of copying them.
+type fileSender struct {
+	rc   syscall.RawConn
+	file *os.File
+	fd   int
+}
+
+func newFileSender(conn net.Conn, buff []byte) (StreamSender, error) {
+	rc, err := rawConn(conn)
+	if err != nil {
+		return nil, err
+	}
+	fd, err := unix.MemfdCreate("ethr", unix.MFD_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create memfd: %w", err)
+	}
+	file := os.NewFile(uintptr(fd), "ethr")
+	_, err = file.Write(buff)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("failed to write buffer to memfd: %w", err)
+	}
+	return &fileSender{rc: rc, file: file, fd: fd}, nil
+}
+
+func (s *fileSender) Send(n int) (int, error) {
+	var written int
+	var sendErr error
+	err := s.rc.Write(func(fd uintptr) bool {
+		offset := int64(0)
+		written, sendErr = unix.Sendfile(int(fd), s.fd, &offset, n)
+		return sendErr != unix.EAGAIN
+	})
+	if err != nil {
+		return 0, err
+	}
+	return written, sendErr
+}
+
+func (s *fileSender) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,167 @@
+package ethr
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// SizeKind is how the sizes of messages are distributed.
+type SizeKind uint8
+
+const (
+	// SizeFixed sends every message with Sizes[0] bytes.
+	SizeFixed SizeKind = iota
+	// SizeUniform picks sizes from Sizes[0] to Sizes[1] with the same odds.
+	SizeUniform
+	// SizeWeighted picks each of Sizes with the odds of its weight.
+	SizeWeighted
+	// SizeEmpirical is SizeWeighted with weights counted from a sample of sizes.
+	SizeEmpirical
+)
+
+func (k SizeKind) String() string {
+	switch k {
+	case SizeUniform:
+		return "uniform"
+	case SizeWeighted:
+		return "weighted"
+	case SizeEmpirical:
+		return "empirical"
+	}
+	return "fixed"
+}
+
+// MaxSizes is how many sizes a distribution can have, the distribution is part of
+// the params the client sends in a handshake message of at most 16KB.
+const MaxSizes = 1024
+
+// SizeDistribution is the sizes of the messages of a test. Without Sizes every
+// message is of the buffer size of the test.
+type SizeDistribution struct {
+	Kind    SizeKind
+	Sizes   []uint32
+	Weights []uint64
+	// sizers of the same seed pick the same sizes in the same order, both ends
+	// of a latency test know the size of each message this way
+	Seed int64
+}
+
+// IsSet tells if messages are of different sizes than the buffer.
+func (d SizeDistribution) IsSet() bool {
+	return len(d.Sizes) > 0
+}
+
+// Max returns the largest message size.
+func (d SizeDistribution) Max() uint32 {
+	max := uint32(0)
+	for _, s := range d.Sizes {
+		if s > max {
+			max = s
+		}
+	}
+	return max
+}
+
+// Validate checks a distribution received from a peer, the messages must fit
+// in a buffer of bufferSize bytes.
+func (d SizeDistribution) Validate(bufferSize uint32) error {
+	if !d.IsSet() {
+		return nil
+	}
+	if len(d.Sizes) > MaxSizes {
+		return fmt.Errorf("%d message sizes, at most %d are supported", len(d.Sizes), MaxSizes)
+	}
+	for _, size := range d.Sizes {
+		if size == 0 || size > bufferSize {
+			return fmt.Errorf("message size %d is not between 1 and the buffer size %d", size, bufferSize)
+		}
+	}
+	switch d.Kind {
+	case SizeUniform:
+		if len(d.Sizes) != 2 || d.Sizes[0] > d.Sizes[1] {
+			return errors.New("uniform sizes need a minimum up to a maximum")
+		}
+	case SizeWeighted, SizeEmpirical:
+		if len(d.Weights) != len(d.Sizes) {
+			return errors.New("every weighted size needs a weight")
+		}
+		total := uint64(0)
+		for _, w := range d.Weights {
+			if w > math.MaxInt64-total {
+				return errors.New("the weights of the sizes add up to too much")
+			}
+			total += w
+		}
+		if total == 0 {
+			return errors.New("the weights of the sizes are all 0")
+		}
+	}
+	return nil
+}
+
+// Sizer picks the sizes of messages from a distribution.
+type Sizer struct {
+	d          SizeDistribution
+	size       int
+	random     *rand.Rand
+	cumulative []uint64
+}
+
+// NewSizer returns a sizer of d, messages are of size bytes when d isn't set.
+func NewSizer(d SizeDistribution, size int) *Sizer {
+	s := &Sizer{d: d, size: size}
+	if !d.IsSet() {
+		return s
+	}
+	s.random = rand.New(rand.NewSource(d.Seed))
+	total := uint64(0)
+	for _, w := range d.Weights {
+		total += w
+		s.cumulative = append(s.cumulative, total)
+	}
+	return s
+}
+
+// Next returns the size of the next message, never more than the size of the
+// sizer nor less than 1 byte.
+func (s *Sizer) Next() int {
+	if !s.d.IsSet() {
+		return s.size
+	}
+	size := int64(s.d.Sizes[0])
+	switch {
+	case s.d.Kind == SizeUniform && len(s.d.Sizes) == 2:
+		min, max := int64(s.d.Sizes[0]), int64(s.d.Sizes[1])
+		if min > max {
+			min, max = max, min
+		}
+		size = min + s.random.Int63n(max-min+1)
+	case (s.d.Kind == SizeWeighted || s.d.Kind == SizeEmpirical) && len(s.cumulative) == len(s.d.Sizes):
+		// weights adding up to more than an int64 wrapped around
+		if total := s.cumulative[len(s.cumulative)-1]; total > 0 && total <= math.MaxInt64 {
+			pick := uint64(s.random.Int63n(int64(total)))
+			i := sort.Search(len(s.cumulative), func(i int) bool { return s.cumulative[i] > pick })
+			size = int64(s.d.Sizes[i])
+		}
+	}
+	if size > int64(s.size) {
+		return s.size
+	}
+	if size < 1 {
+		return 1
+	}
+	return int(size)
+}
+
+// SizeBucket returns the bucket results for messages of size bytes are broken
+// out by, the power of two the size is up to, at least 64.
+func SizeBucket(size int) uint32 {
+	bucket := uint32(64)
+	for bucket < uint32(size) && bucket < 1<<31 {
+		bucket <<= 1
+	}
+	return bucket
+}
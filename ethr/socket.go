@@ -0,0 +1,146 @@
+package ethr
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ErrNotSupported is returned for socket features the platform doesn't have.
+var ErrNotSupported = errors.New("not supported on this platform")
+
+// StreamOptions are the socket options the kernel granted a TCP stream, the
+// buffers can differ from the requested sizes. Linger is -1 when it is off.
+type StreamOptions struct {
+	CongestionControl string
+	NoDelay           bool
+	SendBuffer        int
+	ReceiveBuffer     int
+	MSS               int
+	Linger            time.Duration
+}
+
+func (o StreamOptions) String() string {
+	return fmt.Sprintf("cc: %s nodelay: %t sndbuf: %d rcvbuf: %d mss: %d linger: %s",
+		o.CCString(), o.NoDelay, o.SendBuffer, o.ReceiveBuffer, o.MSS, o.LingerString())
+}
+
+// CCString is the congestion control algorithm, "-" when unknown.
+func (o StreamOptions) CCString() string {
+	if o.CongestionControl == "" {
+		return "-"
+	}
+	return o.CongestionControl
+}
+
+// LingerString is the linger timeout, "off" when linger is off.
+func (o StreamOptions) LingerString() string {
+	if o.Linger < 0 {
+		return "off"
+	}
+	return o.Linger.String()
+}
+
+// ControlSocket runs fn on the socket behind conn.
+func ControlSocket(conn interface{}, fn func(fd uintptr) error) error {
+	rc, err := rawConn(conn)
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	err = rc.Control(func(fd uintptr) {
+		fnErr = fn(fd)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to access socket: %w", err)
+	}
+	return fnErr
+}
+
+// SetStreamOptions sets the socket options of a TCP stream requested in params,
+// on both the client and the server end of the connection.
+func SetStreamOptions(fd uintptr, params ClientParams) error {
+	if len(params.CongestionControl) > 0 {
+		err := setCongestionControl(fd, params.CongestionControl[0])
+		if err != nil {
+			return fmt.Errorf("failed to use congestion control %s: %w", params.CongestionControl[0], err)
+		}
+	}
+	if params.Nagle {
+		err := setSockOptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_NODELAY, 0)
+		if err != nil {
+			return fmt.Errorf("failed to enable Nagle's algorithm: %w", err)
+		}
+	}
+	if params.SendBuffer > 0 {
+		err := setSockOptInt(fd, syscall.SOL_SOCKET, syscall.SO_SNDBUF, int(params.SendBuffer))
+		if err != nil {
+			return fmt.Errorf("failed to set send buffer to %d: %w", params.SendBuffer, err)
+		}
+	}
+	if params.ReceiveBuffer > 0 {
+		err := setSockOptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF, int(params.ReceiveBuffer))
+		if err != nil {
+			return fmt.Errorf("failed to set receive buffer to %d: %w", params.ReceiveBuffer, err)
+		}
+	}
+	if params.MSS > 0 {
+		// the server sets it after the handshake, the MSS it advertised stays
+		err := setSockOptInt(fd, syscall.IPPROTO_TCP, tcpMaxSeg, int(params.MSS))
+		if err != nil {
+			return fmt.Errorf("failed to set MSS to %d: %w", params.MSS, err)
+		}
+	}
+	if params.SetLinger {
+		err := setLinger(fd, params.Linger)
+		if err != nil {
+			return fmt.Errorf("failed to set linger to %ds: %w", params.Linger, err)
+		}
+	}
+	return nil
+}
+
+// GetStreamOptions reads back the socket options in effect on a TCP stream.
+func GetStreamOptions(fd uintptr) (StreamOptions, error) {
+	var o StreamOptions
+	var err error
+	// not every platform can report the algorithm
+	o.CongestionControl, _ = getCongestionControl(fd)
+	noDelay, err := getSockOptInt(fd, syscall.IPPROTO_TCP, syscall.TCP_NODELAY)
+	if err != nil {
+		return o, fmt.Errorf("failed to get TCP_NODELAY: %w", err)
+	}
+	o.NoDelay = noDelay != 0
+	o.SendBuffer, err = getSockOptInt(fd, syscall.SOL_SOCKET, syscall.SO_SNDBUF)
+	if err != nil {
+		return o, fmt.Errorf("failed to get SO_SNDBUF: %w", err)
+	}
+	o.ReceiveBuffer, err = getSockOptInt(fd, syscall.SOL_SOCKET, syscall.SO_RCVBUF)
+	if err != nil {
+		return o, fmt.Errorf("failed to get SO_RCVBUF: %w", err)
+	}
+	o.MSS, err = getSockOptInt(fd, syscall.IPPROTO_TCP, tcpMaxSeg)
+	if err != nil {
+		return o, fmt.Errorf("failed to get TCP_MAXSEG: %w", err)
+	}
+	o.Linger, err = getLinger(fd)
+	if err != nil {
+		return o, fmt.Errorf("failed to get SO_LINGER: %w", err)
+	}
+	return o, nil
+}
+
+// rawConn returns the raw socket behind conn.
+func rawConn(conn interface{}) (syscall.RawConn, error) {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return nil, fmt.Errorf("failed to access socket: %w", os.ErrInvalid)
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to access socket: %w", err)
+	}
+	return rc, nil
+}
@@ -0,0 +1,47 @@
+// +build darwin
+
+package ethr
+
+import (
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const tcpMaxSeg = syscall.TCP_MAXSEG
+
+func setSockOptInt(fd uintptr, level, opt, value int) error {
+	return syscall.SetsockoptInt(int(fd), level, opt, value)
+}
+
+func getSockOptInt(fd uintptr, level, opt int) (int, error) {
+	return syscall.GetsockoptInt(int(fd), level, opt)
+}
+
+func setCongestionControl(fd uintptr, algorithm string) error {
+	return ErrNotSupported
+}
+
+func getCongestionControl(fd uintptr) (string, error) {
+	return "", ErrNotSupported
+}
+
+func setLinger(fd uintptr, seconds int) error {
+	l := syscall.Linger{}
+	if seconds >= 0 {
+		l.Onoff, l.Linger = 1, int32(seconds)
+	}
+	return syscall.SetsockoptLinger(int(fd), syscall.SOL_SOCKET, syscall.SO_LINGER, &l)
+}
+
+func getLinger(fd uintptr) (time.Duration, error) {
+	l, err := unix.GetsockoptLinger(int(fd), unix.SOL_SOCKET, unix.SO_LINGER)
+	if err != nil {
+		return 0, err
+	}
+	if l.Onoff == 0 {
+		return -1, nil
+	}
+	return time.Duration(l.Linger) * time.Second, nil
+}
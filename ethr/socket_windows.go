@@ -0,0 +1,52 @@
+// +build windows
+
+package ethr
+
+import (
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// TCP_MAXSEG can only be read on Windows, not exported by the syscall package
+const tcpMaxSeg = 4
+
+func setSockOptInt(fd uintptr, level, opt, value int) error {
+	if opt == tcpMaxSeg && level == syscall.IPPROTO_TCP {
+		return ErrNotSupported
+	}
+	return syscall.SetsockoptInt(syscall.Handle(fd), level, opt, value)
+}
+
+func getSockOptInt(fd uintptr, level, opt int) (int, error) {
+	return syscall.GetsockoptInt(syscall.Handle(fd), level, opt)
+}
+
+func setCongestionControl(fd uintptr, algorithm string) error {
+	return ErrNotSupported
+}
+
+func getCongestionControl(fd uintptr) (string, error) {
+	return "", ErrNotSupported
+}
+
+func setLinger(fd uintptr, seconds int) error {
+	l := syscall.Linger{}
+	if seconds >= 0 {
+		l.Onoff, l.Linger = 1, int32(seconds)
+	}
+	return syscall.SetsockoptLinger(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_LINGER, &l)
+}
+
+func getLinger(fd uintptr) (time.Duration, error) {
+	l := syscall.Linger{}
+	size := int32(unsafe.Sizeof(l))
+	err := syscall.Getsockopt(syscall.Handle(fd), syscall.SOL_SOCKET, syscall.SO_LINGER, (*byte)(unsafe.Pointer(&l)), &size)
+	if err != nil {
+		return 0, err
+	}
+	if l.Onoff == 0 {
+		return -1, nil
+	}
+	return time.Duration(l.Linger) * time.Second, nil
+}
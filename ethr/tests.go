@@ -13,6 +13,11 @@ const (
 	TestTypePing
 	TestTypeTraceRoute
 	TestTypeMyTraceRoute
+	TestTypeMTU
+	TestTypeLatencyUnderLoad
+	TestTypeRequestResponse
+	TestTypeConnectRequestResponse
+	TestTypeMaxConnections
 	TestTypeUnknown
 )
 
@@ -34,6 +39,16 @@ func (p TestType) MarshalJSON() ([]byte, error) {
 		return []byte("TraceRoute"), nil
 	case TestTypeMyTraceRoute:
 		return []byte("MyTraceRoute"), nil
+	case TestTypeMTU:
+		return []byte("MTU"), nil
+	case TestTypeLatencyUnderLoad:
+		return []byte("LatencyUnderLoad"), nil
+	case TestTypeRequestResponse:
+		return []byte("RequestResponse"), nil
+	case TestTypeConnectRequestResponse:
+		return []byte("ConnectRequestResponse"), nil
+	case TestTypeMaxConnections:
+		return []byte("MaxConnections"), nil
 	}
 	return []byte("UNKNOWN"), nil
 }
@@ -56,6 +71,16 @@ func (p TestType) String() string {
 		return "TraceRoute"
 	case TestTypeMyTraceRoute:
 		return "MyTraceRoute"
+	case TestTypeMTU:
+		return "MTU"
+	case TestTypeLatencyUnderLoad:
+		return "LatencyUnderLoad"
+	case TestTypeRequestResponse:
+		return "RequestResponse"
+	case TestTypeConnectRequestResponse:
+		return "ConnectRequestResponse"
+	case TestTypeMaxConnections:
+		return "MaxConnections"
 	}
 	return "UNKNOWN"
 }
@@ -78,6 +103,16 @@ func ParseTestType(s string) TestType {
 		return TestTypeTraceRoute
 	case "MTR":
 		return TestTypeMyTraceRoute
+	case "MTU":
+		return TestTypeMTU
+	case "LL":
+		return TestTypeLatencyUnderLoad
+	case "RR":
+		return TestTypeRequestResponse
+	case "CRR":
+		return TestTypeConnectRequestResponse
+	case "MC":
+		return TestTypeMaxConnections
 	}
 	return TestTypeUnknown
 }
@@ -0,0 +1,94 @@
+package ethr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync/atomic"
+)
+
+// PayloadStream is an endless pseudo-random byte stream of a seed. Every word
+// of the stream is computed from its position, so the receiver knows what each
+// byte should be without the sender telling it.
+type PayloadStream struct {
+	seed   uint64
+	offset uint64
+}
+
+func NewPayloadStream(seed int64) *PayloadStream {
+	return &PayloadStream{seed: uint64(seed)}
+}
+
+// word is the splitmix64 output for the i-th word of the stream.
+func (s *PayloadStream) word(i uint64) uint64 {
+	z := s.seed + (i+1)*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// Offset returns the position of the next byte of the stream.
+func (s *PayloadStream) Offset() uint64 {
+	return s.offset
+}
+
+// Fill writes the next len(b) bytes of the stream to b.
+func (s *PayloadStream) Fill(b []byte) {
+	for len(b) > 0 {
+		if s.offset%8 == 0 && len(b) >= 8 {
+			binary.LittleEndian.PutUint64(b, s.word(s.offset/8))
+			b = b[8:]
+			s.offset += 8
+			continue
+		}
+		b[0] = byte(s.word(s.offset/8) >> (8 * (s.offset % 8)))
+		b = b[1:]
+		s.offset++
+	}
+}
+
+// StreamVerifier checks that the bytes received on a stream are those of the
+// payload stream of the sender. The counters are updated atomically.
+type StreamVerifier struct {
+	stream   *PayloadStream
+	buff     []byte
+	Verified uint64
+	// Corrupted counts the bytes that didn't match, FirstCorruption is the
+	// offset in the stream of the first one
+	Corrupted       uint64
+	FirstCorruption uint64
+}
+
+func NewStreamVerifier(seed int64) *StreamVerifier {
+	return &StreamVerifier{stream: NewPayloadStream(seed)}
+}
+
+// Check compares b with the next len(b) bytes of the stream, it returns true
+// when b holds the first corruption of the stream.
+func (v *StreamVerifier) Check(b []byte) bool {
+	if cap(v.buff) < len(b) {
+		v.buff = make([]byte, len(b))
+	}
+	start := v.stream.Offset()
+	expected := v.buff[:len(b)]
+	v.stream.Fill(expected)
+	if bytes.Equal(b, expected) {
+		atomic.AddUint64(&v.Verified, uint64(len(b)))
+		return false
+	}
+	first := false
+	corrupted := uint64(0)
+	for i := range b {
+		if b[i] != expected[i] {
+			if corrupted == 0 && atomic.LoadUint64(&v.Corrupted) == 0 {
+				atomic.StoreUint64(&v.FirstCorruption, start+uint64(i))
+				first = true
+			}
+			corrupted++
+		}
+	}
+	if corrupted > 0 {
+		atomic.AddUint64(&v.Corrupted, corrupted)
+	}
+	atomic.AddUint64(&v.Verified, uint64(len(b)))
+	return first
+}
@@ -8,6 +8,8 @@ import (
 	"runtime"
 	"syscall"
 
+	"weavelab.xyz/ethr/server/http"
+	"weavelab.xyz/ethr/server/icmp"
 	"weavelab.xyz/ethr/server/udp"
 
 	"weavelab.xyz/ethr/session"
@@ -61,6 +63,9 @@ func main() {
 			IPVersion: config.IPVersion,
 			LocalIP:   config.LocalIP,
 			LocalPort: config.Port,
+			CertFile:  config.CertFile,
+			KeyFile:   config.KeyFile,
+			BatchSize: config.BatchSize,
 		}
 
 		term := serverUi.NewUI(config.ShowUI)
@@ -71,14 +76,27 @@ func main() {
 		stats.StartTimer()
 		defer stats.StopTimer()
 
-		logger.Info("Listening on TCP & UDP port %d", cfg.LocalPort)
+		logger.Info("Listening on TCP, UDP, HTTP & HTTPS port %d", cfg.LocalPort)
 		err := udp.Serve(ctx, &cfg, udp.NewHandler(logger))
 		if err != nil {
 			fmt.Printf("%v", err)
 			os.Exit(1)
 		}
 
-		err = tcp.Serve(ctx, &cfg, tcp.NewHandler(logger))
+		err = icmp.Serve(ctx, &cfg, icmp.NewHandler(logger))
+		if err != nil {
+			// ICMP tests work without the server, it just can't show them
+			logger.Info("Not showing ICMP peers: %v", err)
+		}
+
+		httpHandler := http.NewHandler(ctx, logger)
+		httpsHandler, err := http.NewTLSHandler(httpHandler, cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			fmt.Printf("%v", err)
+			os.Exit(1)
+		}
+
+		err = tcp.Serve(ctx, &cfg, tcp.NewHandler(logger, httpHandler, httpsHandler))
 		if err != nil {
 			fmt.Printf("%v", err)
 			os.Exit(1)
@@ -96,6 +114,39 @@ func main() {
 			WarmupCount: uint32(config.WarmupCount),
 			BwRate:      config.BandwidthRate,
 			ToS:         uint8(config.TOS),
+			NoKeepAlive: config.NoKeepAlive,
+
+			Bidirectional: config.Bidirectional,
+
+			CongestionControl: config.CongestionControl,
+			Nagle:             config.Nagle,
+			SendBuffer:        uint32(config.SendBuffer),
+			ReceiveBuffer:     uint32(config.ReceiveBuffer),
+			MSS:               uint32(config.MSS),
+			SetLinger:         config.SetLinger,
+			Linger:            config.Linger,
+			SendMode:          config.SendMode,
+			BatchSize:         uint32(config.BatchSize),
+			GSO:               config.GSO,
+			Pacing:            config.Pacing,
+			Burst:             uint32(config.Burst),
+			SharedRate:        config.SharedRate,
+			Profile:           config.Profile,
+			Sizes:             config.Sizes,
+			Content:           config.Content,
+			ResponseSize:      uint32(config.ResponseSize),
+			RampRate:          uint32(config.RampRate),
+			MaxConnections:    uint32(config.MaxConnections),
+			KeepActive:        config.KeepActive,
+			Verify:            config.Verify,
+			VerifySeed:        config.VerifySeed,
+
+			Paris: config.Paris,
+			Flows: uint32(config.Flows),
+
+			TLSVersions:  config.TLSVersions,
+			CipherSuites: config.CipherSuites,
+			IgnoreCert:   config.IgnoreCert,
 		}
 		c, err := client.NewClient(config.IsExternal, logger, params, config.RemoteIP, config.Port, config.LocalIP, config.LocalPort)
 		if err != nil {
@@ -115,6 +166,7 @@ func main() {
 			fmt.Printf("%v", err)
 			os.Exit(1)
 		}
+		term.PrintSummary(test)
 	}
 }
 
@@ -10,4 +10,8 @@ type Config struct {
 	IPVersion ethr.IPVersion
 	LocalIP   net.IP
 	LocalPort uint16
+	CertFile  string
+	KeyFile   string
+	// UDP datagrams received per recvmmsg call
+	BatchSize int
 }
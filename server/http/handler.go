@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+// maxResponseSize caps the response body a client can ask for with the size query parameter.
+const maxResponseSize = 1 << 30
+
+type Handler struct {
+	ctx      context.Context
+	logger   ethr.Logger
+	listener *connListener
+	buff     []byte
+}
+
+func NewHandler(ctx context.Context, logger ethr.Logger) Handler {
+	buff := make([]byte, 64*1024)
+	for i := range buff {
+		buff[i] = byte(i)
+	}
+	h := Handler{
+		ctx:      ctx,
+		logger:   logger,
+		listener: newConnListener(),
+		buff:     buff,
+	}
+
+	srv := &http.Server{
+		Handler:     h,
+		IdleTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = srv.Close()
+	}()
+	go func() {
+		err := srv.Serve(h.listener)
+		if err != nil && err != http.ErrServerClosed && err != errListenerClosed {
+			h.logger.Error("HTTP server stopped: %v", err)
+		}
+	}()
+	return h
+}
+
+// HandleConn passes a connection that was identified as HTTP by the TCP server to
+// net/http. Tests are tracked per request, so the test passed in is unused.
+func (h Handler) HandleConn(ctx context.Context, unused *session.Test, conn net.Conn) {
+	select {
+	case h.listener.conns <- conn:
+	case <-ctx.Done():
+		_ = conn.Close()
+	}
+}
+
+// ServeHTTP discards the request body and responds with the number of bytes
+// requested with the size query parameter. POST with an empty size is used for
+// upload bandwidth, GET with size for download bandwidth and POST with size for
+// latency tests.
+func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	test := h.getTest(r)
+
+	n, err := io.CopyBuffer(ioutil.Discard, r.Body, make([]byte, 64*1024))
+	if err != nil {
+		h.logger.Debug("Error receiving HTTP request body from %s: %v", r.RemoteAddr, err)
+		return
+	}
+
+	size, _ := strconv.ParseInt(r.URL.Query().Get("size"), 10, 64)
+	if size < 0 || size > maxResponseSize {
+		http.Error(w, "invalid size", http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.WriteHeader(http.StatusOK)
+
+	sent := int64(0)
+	for sent < size {
+		chunk := h.buff
+		if remaining := size - sent; remaining < int64(len(chunk)) {
+			chunk = chunk[:remaining]
+		}
+		written, err := w.Write(chunk)
+		sent += int64(written)
+		if err != nil {
+			h.logger.Debug("Error sending HTTP response body to %s: %v", r.RemoteAddr, err)
+			break
+		}
+	}
+
+	if test != nil {
+		test.AddIntermediateResult(session.TestResult{
+			Success: true,
+			Error:   nil,
+			Body: payloads.ServerPayload{
+				ConnectionsPerSecond: 1,
+				Bandwidth:            uint64(n + sent),
+			},
+		})
+	}
+}
+
+func (h Handler) getTest(r *http.Request) *session.Test {
+	remote, port, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		h.logger.Error("RemoteAddr: Split host port failed: %v", err)
+		return nil
+	}
+	rIP := net.ParseIP(remote)
+	rPort, _ := strconv.Atoi(port)
+	protocol := ethr.HTTP
+	if r.TLS != nil {
+		protocol = ethr.HTTPS
+	}
+	test, isNew := session.CreateOrGetTest(rIP, uint16(rPort), protocol, ethr.TestTypeServer, ethr.ClientParams{}, ServerAggregator, time.Second)
+	if isNew {
+		h.logger.Debug("Creating %s test from server: %v, lastAccess: %v", protocol, r.RemoteAddr, time.Now())
+		go test.Session.PollInactive(h.ctx, 100*time.Millisecond) // cleanup based on last access
+	}
+	return test
+}
+
+func ServerAggregator(nanos uint64, intermediateResults []session.TestResult) session.TestResult {
+	requests := uint64(0)
+	totalBandwidth := uint64(0)
+
+	for _, r := range intermediateResults {
+		// ignore failed results
+		switch body := r.Body.(type) {
+		case payloads.ServerPayload:
+			requests += body.ConnectionsPerSecond
+			totalBandwidth += body.Bandwidth
+		default:
+			// do nothing, drop unknowns
+		}
+	}
+
+	return session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body: payloads.ServerPayload{
+			ConnectionsPerSecond: 1e9 * requests / nanos,
+			Bandwidth:            1e9 * totalBandwidth / nanos,
+		},
+	}
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+
+	"weavelab.xyz/ethr/session"
+)
+
+// TLSHandler terminates TLS on connections identified as HTTPS by the TCP
+// server before passing them on to the HTTP server.
+type TLSHandler struct {
+	Handler
+	config *tls.Config
+}
+
+// NewTLSHandler loads the certificate and key from the given files, or creates a
+// self-signed certificate in memory when no files are given.
+func NewTLSHandler(h Handler, certFile, keyFile string) (TLSHandler, error) {
+	var cert tls.Certificate
+	var err error
+	if certFile != "" || keyFile != "" {
+		cert, err = tls.LoadX509KeyPair(certFile, keyFile)
+	} else {
+		cert, err = createSelfSignedCert()
+	}
+	if err != nil {
+		return TLSHandler{}, fmt.Errorf("failed to initialize server certificate: %w", err)
+	}
+
+	return TLSHandler{
+		Handler: h,
+		config: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS10,
+		},
+	}, nil
+}
+
+func (h TLSHandler) HandleConn(ctx context.Context, unused *session.Test, conn net.Conn) {
+	h.Handler.HandleConn(ctx, unused, tls.Server(conn, h.config))
+}
+
+func createSelfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"Ethr"}, CommonName: "ethr"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  key,
+	}, nil
+}
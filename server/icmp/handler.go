@@ -0,0 +1,99 @@
+package icmp
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+type Handler struct {
+	logger ethr.Logger
+}
+
+func NewHandler(logger ethr.Logger) Handler {
+	return Handler{
+		logger: logger,
+	}
+}
+
+func (h Handler) HandleConn(ctx context.Context, unused *session.Test, conn net.Conn) {
+	pc, ok := conn.(net.PacketConn)
+	if !ok {
+		return
+	}
+	defer conn.Close()
+
+	readBuffer := make([]byte, 64*1024)
+	for {
+		bytesRead, raddr, err := pc.ReadFrom(readBuffer)
+		if err != nil {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+			h.logger.Debug("Error receiving ICMP packet: %v", err)
+			continue
+		}
+
+		ipAddr, ok := raddr.(*net.IPAddr)
+		if !ok {
+			continue
+		}
+		// raw sockets see all ICMP traffic, only echo requests come from peers
+		protocol := ethr.ICMPv6
+		if ipAddr.IP.To4() != nil {
+			protocol = ethr.ICMPv4
+		}
+		msg, err := icmp.ParseMessage(protocol, readBuffer[:bytesRead])
+		if err != nil || (msg.Type != ipv4.ICMPTypeEcho && msg.Type != ipv6.ICMPTypeEchoRequest) {
+			continue
+		}
+
+		test, isNew := session.CreateOrGetTest(ipAddr.IP, 0, ethr.ICMP, ethr.TestTypeServer, ethr.ClientParams{}, ServerAggregator, time.Second)
+		if test == nil {
+			continue
+		}
+		if isNew {
+			h.logger.Info("New ICMP peer: %v", ipAddr)
+			go test.Session.PollInactive(ctx, 100*time.Millisecond) // cleanup based on last access
+		}
+		test.AddIntermediateResult(session.TestResult{
+			Success: true,
+			Error:   nil,
+			Body: payloads.RawBandwidthPayload{
+				Bandwidth:        uint64(bytesRead),
+				PacketsPerSecond: 1,
+			},
+		})
+	}
+}
+
+func ServerAggregator(nanos uint64, intermediateResults []session.TestResult) session.TestResult {
+	totalBandwidth := uint64(0)
+	totalPackets := uint64(0)
+
+	for _, r := range intermediateResults {
+		if body, ok := r.Body.(payloads.RawBandwidthPayload); ok {
+			totalBandwidth += body.Bandwidth
+			totalPackets += body.PacketsPerSecond
+		}
+	}
+
+	return session.TestResult{
+		Success: true,
+		Error:   nil,
+		Body: payloads.ServerPayload{
+			PacketsPerSecond: 1e9 * totalPackets / nanos,
+			Bandwidth:        1e9 * totalBandwidth / nanos,
+		},
+	}
+}
@@ -0,0 +1,53 @@
+package icmp
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/server"
+)
+
+// Serve watches the ICMP echo requests sent to the server. The kernel answers
+// them, the raw sockets only let the server show its ICMP peers and therefore
+// need admin rights. Serve fails only when neither IPv4 nor IPv6 could be watched.
+func Serve(ctx context.Context, cfg *server.Config, h Handler) error {
+	versions := []ethr.IPVersion{cfg.IPVersion}
+	addr := ""
+	if cfg.LocalIP != nil && !cfg.LocalIP.IsUnspecified() {
+		addr = cfg.LocalIP.String()
+		if cfg.LocalIP.To4() != nil {
+			versions = []ethr.IPVersion{ethr.IPv4}
+		} else {
+			versions = []ethr.IPVersion{ethr.IPv6}
+		}
+	} else if cfg.IPVersion == ethr.IPAny {
+		versions = []ethr.IPVersion{ethr.IPv4, ethr.IPv6}
+	}
+	var firstErr error
+	listening := 0
+	for _, v := range versions {
+		l, err := net.ListenPacket(ethr.ICMPVersion(v), addr)
+		if err != nil {
+			err = fmt.Errorf("error listening on %s for ICMP tests: %w", ethr.ICMPVersion(v), err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		listening++
+		go func() {
+			<-ctx.Done()
+			_ = l.Close()
+		}()
+		go h.HandleConn(ctx, nil, l.(net.Conn))
+	}
+	if listening == 0 {
+		return firstErr
+	}
+	if firstErr != nil {
+		h.logger.Info("Showing ICMP peers of one IP version only: %v", firstErr)
+	}
+	return nil
+}
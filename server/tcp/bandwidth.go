@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sync/atomic"
 
 	"weavelab.xyz/ethr/ethr"
 	"weavelab.xyz/ethr/session"
@@ -11,16 +12,44 @@ import (
 	"weavelab.xyz/ethr/stats"
 )
 
+// TestBandwidth receives from the client, sends to it in reverse mode or does both
+// at once for bidirectional tests.
 func (h Handler) TestBandwidth(ctx context.Context, test *session.Test, clientParam ethr.ClientParams, conn net.Conn) error {
-	size := clientParam.BufferSize
-	buff := make([]byte, size)
-	for i := uint32(0); i < size; i++ {
-		buff[i] = byte(i)
-	}
-	bufferLen := len(buff)
-	totalBytesToSend := test.ClientParam.BwRate
-	sentBytes := uint64(0)
-	start, waitTime, bytesToSend := stats.BeginThrottle(totalBytesToSend, bufferLen)
+	if clientParam.Bidirectional {
+		// the receiving side ends the test, closing the connection stops the sender
+		go func() {
+			_ = h.sendBandwidth(ctx, test, clientParam, conn)
+		}()
+		return h.receiveBandwidth(ctx, test, clientParam, conn)
+	} else if clientParam.Reverse {
+		return h.sendBandwidth(ctx, test, clientParam, conn)
+	}
+	return h.receiveBandwidth(ctx, test, clientParam, conn)
+}
+
+func (h Handler) sendBandwidth(ctx context.Context, test *session.Test, clientParam ethr.ClientParams, conn net.Conn) error {
+	buff := make([]byte, clientParam.BufferSize)
+	filler, err := clientParam.Content.NewFiller()
+	if err != nil {
+		return fmt.Errorf("failed to fill the buffer for bandwidth test: %w", err)
+	}
+	filler.Fill(buff)
+	sender, err := ethr.NewStreamSender(conn, buff, clientParam.SendMode, nil)
+	if err != nil {
+		h.logger.Error("Failed to send with %s, copying instead: %v", clientParam.SendMode, err)
+		sender, _ = ethr.NewStreamSender(conn, buff, ethr.SendCopy, nil)
+	}
+	defer sender.Close()
+	// the client sends the params of each stream, see ethr.ClientParams.ForStream
+	limiter, err := stats.StreamLimiter(clientParam, conn, nil)
+	if err != nil {
+		h.logger.Error("Failed to pace in the kernel, pacing in user space: %v", err)
+	}
+	sizer := ethr.NewSizer(clientParam.Sizes, len(buff))
+	var stream *ethr.PayloadStream
+	if clientParam.Verify {
+		stream = ethr.NewPayloadStream(clientParam.VerifySeed)
+	}
 	for {
 		select {
 		case <-ctx.Done():
@@ -28,26 +57,76 @@ func (h Handler) TestBandwidth(ctx context.Context, test *session.Test, clientPa
 		default:
 		}
 
-		n := 0
-		var err error
-		if clientParam.Reverse {
-			n, err = conn.Write(buff[:bytesToSend])
-		} else {
-			n, err = conn.Read(buff)
+		bytesToSend := limiter.Limit(sizer.Next())
+		if stream != nil {
+			stream.Fill(buff[:bytesToSend])
+		} else if !filler.Static() {
+			filler.Fill(buff[:bytesToSend])
+		}
+		if !limiter.Wait(ctx.Done(), bytesToSend) {
+			return nil
 		}
+		n, err := sender.Send(bytesToSend)
 		if err != nil {
-			return fmt.Errorf("error sending/receiving data on a connection for bandwidth test: %w", err)
+			return fmt.Errorf("error sending data on a connection for bandwidth test: %w", err)
 		}
 		test.AddIntermediateResult(session.TestResult{
 			Success: true,
 			Error:   nil,
 			Body: payloads.BandwidthPayload{
-				TotalBandwidth: uint64(size),
+				TotalBandwidth: uint64(n),
 			},
 		})
-		if clientParam.Reverse {
-			sentBytes += uint64(n)
-			start, waitTime, sentBytes, bytesToSend = stats.EnforceThrottle(start, waitTime, totalBytesToSend, sentBytes, bufferLen)
+	}
+}
+
+func (h Handler) receiveBandwidth(ctx context.Context, test *session.Test, clientParam ethr.ClientParams, conn net.Conn) error {
+	buff := make([]byte, clientParam.BufferSize)
+	var verifier *ethr.StreamVerifier
+	if clientParam.Verify {
+		verifier = ethr.NewStreamVerifier(clientParam.VerifySeed)
+		defer h.reportIntegrity(test, clientParam, conn, verifier)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		n, err := conn.Read(buff)
+		if err != nil {
+			return fmt.Errorf("error receiving data on a connection for bandwidth test: %w", err)
 		}
+		if verifier != nil && verifier.Check(buff[:n]) {
+			h.logger.Error("Data received from %s is corrupted at offset %d of the stream", conn.RemoteAddr(), atomic.LoadUint64(&verifier.FirstCorruption))
+		}
+		test.AddIntermediateResult(session.TestResult{
+			Success: true,
+			Error:   nil,
+			Body: payloads.BandwidthPayload{
+				TotalBandwidth: uint64(n),
+			},
+		})
+	}
+}
+
+// reportIntegrity logs what was verified of the stream the client sent and,
+// unless the client receives on the same connection, sends it to the client
+// once it stopped sending.
+func (h Handler) reportIntegrity(test *session.Test, clientParam ethr.ClientParams, conn net.Conn, verifier *ethr.StreamVerifier) {
+	verified := atomic.LoadUint64(&verifier.Verified)
+	corrupted := atomic.LoadUint64(&verifier.Corrupted)
+	if corrupted > 0 {
+		h.logger.Error("Data received from %s: %d of %d bytes corrupted, the first at offset %d", conn.RemoteAddr(), corrupted, verified, atomic.LoadUint64(&verifier.FirstCorruption))
+	} else {
+		h.logger.Info("Data received from %s: %d bytes verified", conn.RemoteAddr(), verified)
+	}
+	if clientParam.Bidirectional {
+		return
+	}
+	err := test.Session.Send(conn, session.CreateIntegrityMsg(verifier))
+	if err != nil {
+		h.logger.Debug("Failed to send the verified integrity to %s: %v", conn.RemoteAddr(), err)
 	}
 }
@@ -3,6 +3,7 @@ package tcp
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 	"syscall"
 	"time"
@@ -10,16 +11,23 @@ import (
 	"weavelab.xyz/ethr/session/payloads"
 
 	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/server"
 	"weavelab.xyz/ethr/session"
 )
 
 type Handler struct {
 	logger ethr.Logger
+	http   server.Handler
+	https  server.Handler
 }
 
-func NewHandler(logger ethr.Logger) Handler {
+// NewHandler creates the TCP handler, connections carrying HTTP requests or TLS
+// handshakes are passed on to httpHandler or httpsHandler as all share the same port.
+func NewHandler(logger ethr.Logger, httpHandler server.Handler, httpsHandler server.Handler) Handler {
 	return Handler{
 		logger: logger,
+		http:   httpHandler,
+		https:  httpsHandler,
 	}
 }
 
@@ -32,7 +40,9 @@ func (h Handler) HandleConn(ctx context.Context, test *session.Test, conn net.Co
 		Body:    payloads.ConnectionsPerSecondPayload{Connections: 1},
 	})
 
-	testID, clientParam, err := test.Session.HandshakeWithClient(conn)
+	testID, clientParam, err := test.Session.HandshakeWithClient(conn, func(testID ethr.TestID, clientParam ethr.ClientParams) (ethr.StreamOptions, error) {
+		return h.prepareStream(conn, testID, clientParam)
+	})
 	if err != nil {
 		//// For ConnectionsPerSecond and Ping tests, there is no deterministic way to know when the test starts
 		//// from the client side and when it ends. This defer function ensures that test is not
@@ -55,11 +65,43 @@ func (h Handler) HandleConn(ctx context.Context, test *session.Test, conn net.Co
 			_ = h.TestBandwidth(ctx, test, clientParam, conn)
 		} else if testID.Type == ethr.TestTypeLatency {
 			_ = h.TestLatency(ctx, test, clientParam, conn)
+		} else if testID.Type == ethr.TestTypeRequestResponse || testID.Type == ethr.TestTypeConnectRequestResponse {
+			_ = h.TestRequestResponse(ctx, test, clientParam, conn)
+		} else if testID.Type == ethr.TestTypeMaxConnections {
+			_ = h.TestMaxConnections(ctx, conn)
 		}
 		session.DeleteTest(test)
 	}
 }
 
+// prepareStream checks the params of a stream and sets the socket options they
+// request before the stream is acknowledged, the server end of a stream uses the
+// same socket options as the client end.
+func (h Handler) prepareStream(conn net.Conn, testID ethr.TestID, clientParam ethr.ClientParams) (ethr.StreamOptions, error) {
+	var options ethr.StreamOptions
+	if err := clientParam.Sizes.Validate(clientParam.BufferSize); err != nil {
+		return options, fmt.Errorf("refusing the message sizes requested by the client: %w", err)
+	}
+	if testID.Protocol != ethr.TCP {
+		return options, nil
+	}
+	err := ethr.ControlSocket(conn, func(fd uintptr) error {
+		err := ethr.SetStreamOptions(fd, clientParam)
+		if err != nil {
+			return err
+		}
+		options, err = ethr.GetStreamOptions(fd)
+		return err
+	})
+	if err != nil {
+		return options, fmt.Errorf("failed to set socket options requested by the client: %w", err)
+	}
+	if clientParam.HasStreamOptions() {
+		h.logger.Info("Socket options of stream from %s: %s", conn.RemoteAddr(), options)
+	}
+	return options, nil
+}
+
 func ServerAggregator(nanos uint64, intermediateResults []session.TestResult) session.TestResult {
 	connections := uint64(0)
 	totalBandwidth := uint64(0)
@@ -16,6 +16,8 @@ func (h Handler) TestLatency(ctx context.Context, test *session.Test, clientPara
 	bytes := make([]byte, clientParam.BufferSize)
 	rttCount := clientParam.RttCount
 	latencyNumbers := make([]time.Duration, rttCount)
+	// the client draws the size of each message it sends from the same sequence
+	sizer := ethr.NewSizer(clientParam.Sizes, len(bytes))
 	for {
 		select {
 		case <-ctx.Done():
@@ -23,18 +25,20 @@ func (h Handler) TestLatency(ctx context.Context, test *session.Test, clientPara
 		default:
 		}
 
-		_, err := io.ReadFull(conn, bytes)
+		last := sizer.Next()
+		_, err := io.ReadFull(conn, bytes[:last])
 		if err != nil {
 			return fmt.Errorf("error receiving data for latency tests: %w", err)
 		}
 		for i := uint32(0); i < rttCount; i++ {
 			s1 := time.Now()
-			_, err = conn.Write(bytes)
+			_, err = conn.Write(bytes[:last])
 			if err != nil {
 				return fmt.Errorf("error sending data for latency test: %w", err)
 
 			}
-			_, err = io.ReadFull(conn, bytes)
+			last = sizer.Next()
+			_, err = io.ReadFull(conn, bytes[:last])
 			if err != nil {
 				return fmt.Errorf("error receiving data for latency test: %w", err)
 
@@ -0,0 +1,34 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+)
+
+// TestMaxConnections holds the connection until the client closes it, echoing
+// the bytes the client sends to keep it active.
+func (h Handler) TestMaxConnections(ctx context.Context, conn net.Conn) error {
+	buff := make([]byte, 1)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		n, err := conn.Read(buff)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error receiving on a held connection: %w", err)
+		}
+		_, err = conn.Write(buff[:n])
+		if err != nil {
+			return fmt.Errorf("error sending on a held connection: %w", err)
+		}
+	}
+}
@@ -65,19 +65,34 @@ func Serve(ctx context.Context, cfg *server.Config, h Handler) error {
 		case <-ctx.Done():
 			return nil
 		case conn := <-conns:
-			conn.RemoteAddr()
-			remote, port, err := net.SplitHostPort(conn.RemoteAddr().String())
-			if err != nil {
-				h.logger.Error("RemoteAddr: Split host port failed: %v", err)
-				continue
-			}
-			rIP := net.ParseIP(remote)
-			rPort, _ := strconv.Atoi(port)
-			test, _ := session.CreateOrGetTest(rIP, uint16(rPort), ethr.TCP, ethr.TestTypeServer, ethr.ClientParams{}, ServerAggregator, time.Second)
-			if test == nil {
-				continue
-			}
-			go h.HandleConn(ctx, test, conn)
+			go h.dispatchConn(ctx, conn)
 		}
 	}
 }
+
+func (h Handler) dispatchConn(ctx context.Context, conn net.Conn) {
+	sniffed := sniffConn(conn)
+	if sniffed.isHTTP() && h.http != nil {
+		h.http.HandleConn(ctx, nil, sniffed)
+		return
+	}
+	if sniffed.isTLS() && h.https != nil {
+		h.https.HandleConn(ctx, nil, sniffed)
+		return
+	}
+
+	remote, port, err := net.SplitHostPort(conn.RemoteAddr().String())
+	if err != nil {
+		h.logger.Error("RemoteAddr: Split host port failed: %v", err)
+		_ = conn.Close()
+		return
+	}
+	rIP := net.ParseIP(remote)
+	rPort, _ := strconv.Atoi(port)
+	test, _ := session.CreateOrGetTest(rIP, uint16(rPort), ethr.TCP, ethr.TestTypeServer, ethr.ClientParams{}, ServerAggregator, time.Second)
+	if test == nil {
+		_ = conn.Close()
+		return
+	}
+	h.HandleConn(ctx, test, sniffed)
+}
@@ -0,0 +1,77 @@
+package tcp
+
+import (
+	"io"
+	"net"
+	"os"
+	"syscall"
+)
+
+// Ethr control messages start with a 4 byte big endian length that is never
+// larger than 16K, so the first 4 bytes of a connection are enough to tell
+// them apart from an HTTP request line or a TLS record sent to the same port.
+const sniffLen = 4
+
+var httpMethods = []string{"GET ", "POST", "PUT ", "HEAD"}
+
+const (
+	tlsRecordHandshake = 0x16
+	tlsMajorVersion    = 0x03
+)
+
+// prefixConn replays the bytes consumed while sniffing a connection before
+// reading from the underlying connection again.
+type prefixConn struct {
+	net.Conn
+	prefix []byte
+	err    error
+}
+
+func sniffConn(conn net.Conn) *prefixConn {
+	prefix := make([]byte, sniffLen)
+	n, err := io.ReadFull(conn, prefix)
+	return &prefixConn{
+		Conn:   conn,
+		prefix: prefix[:n],
+		err:    err,
+	}
+}
+
+func (c *prefixConn) Read(b []byte) (int, error) {
+	if len(c.prefix) > 0 {
+		n := copy(b, c.prefix)
+		c.prefix = c.prefix[n:]
+		return n, nil
+	}
+	if c.err != nil {
+		err := c.err
+		c.err = nil
+		return 0, err
+	}
+	return c.Conn.Read(b)
+}
+
+// SyscallConn gives access to the socket of the underlying connection.
+func (c *prefixConn) SyscallConn() (syscall.RawConn, error) {
+	sc, ok := c.Conn.(syscall.Conn)
+	if !ok {
+		return nil, os.ErrInvalid
+	}
+	return sc.SyscallConn()
+}
+
+func (c *prefixConn) isHTTP() bool {
+	if len(c.prefix) < sniffLen {
+		return false
+	}
+	for _, m := range httpMethods {
+		if string(c.prefix) == m {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *prefixConn) isTLS() bool {
+	return len(c.prefix) == sniffLen && c.prefix[0] == tlsRecordHandshake && c.prefix[1] == tlsMajorVersion
+}
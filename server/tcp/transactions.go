@@ -0,0 +1,50 @@
+package tcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+)
+
+// maxTransactionSize is the largest request or response a client can ask for,
+// as on the client.
+const maxTransactionSize = 1000 * 1000 * 1000
+
+// TestRequestResponse answers every request on the connection until the client
+// closes it, after one request for TCP_CRR tests.
+func (h Handler) TestRequestResponse(ctx context.Context, test *session.Test, clientParam ethr.ClientParams, conn net.Conn) error {
+	if clientParam.BufferSize > maxTransactionSize || clientParam.ResponseSize > maxTransactionSize {
+		err := fmt.Errorf("request of %d bytes or response of %d bytes above the maximum of 1GB", clientParam.BufferSize, clientParam.ResponseSize)
+		h.logger.Error("Refusing request/response test from %s: %v", conn.RemoteAddr(), err)
+		return err
+	}
+	request := make([]byte, clientParam.BufferSize)
+	response := make([]byte, clientParam.ResponseSize)
+	for i := range response {
+		response[i] = byte(i)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		default:
+		}
+
+		_, err := io.ReadFull(conn, request)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error receiving request: %w", err)
+		}
+		_, err = conn.Write(response)
+		if err != nil {
+			return fmt.Errorf("error sending response: %w", err)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package udp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"net"
+)
+
+// cookies proves a client receives at the address its DatagramStart comes from,
+// the server only streams to an address once it was echoed the cookie of it.
+// Cookies are derived from a secret of the server, nothing is stored per address.
+type cookies struct {
+	secret []byte
+}
+
+func newCookies() (*cookies, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return &cookies{secret: secret}, nil
+}
+
+func (c *cookies) of(addr *net.UDPAddr) uint64 {
+	mac := hmac.New(sha256.New, c.secret)
+	_, _ = mac.Write([]byte(addr.String()))
+	return binary.BigEndian.Uint64(mac.Sum(nil))
+}
+
+// valid tells if cookie was handed out to addr.
+func (c *cookies) valid(addr *net.UDPAddr, cookie uint64) bool {
+	return cookie != 0 && cookie == c.of(addr)
+}
@@ -5,65 +5,172 @@ import (
 	"net"
 	"time"
 
+	"golang.org/x/net/ipv4"
+
 	"weavelab.xyz/ethr/session/payloads"
 
 	"weavelab.xyz/ethr/ethr"
 	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/stats"
 )
 
 type Handler struct {
-	logger ethr.Logger
+	logger    ethr.Logger
+	streams   *streams
+	senders   *senders
+	cookies   *cookies
+	batchSize int
 }
 
 func NewHandler(logger ethr.Logger) Handler {
 	return Handler{
-		logger: logger,
+		logger:  logger,
+		streams: newStreams(),
+		senders: newSenders(),
 	}
 }
 
 func (h Handler) HandleConn(ctx context.Context, unused *session.Test, conn net.Conn) {
-	// For UDP, allocate buffer that can accomodate largest UDP datagram.
-	readBuffer := make([]byte, 64*1024)
-
-	var err error
-	for err == nil {
-		if udpConn, ok := conn.(*net.UDPConn); ok {
-			select {
-			case <-ctx.Done():
+	udpConn, ok := conn.(*net.UDPConn)
+	if !ok {
+		return
+	}
+	count := h.batchSize
+	if count < 1 {
+		count = 1
+	}
+	// For UDP, allocate buffers that can accomodate largest UDP datagram.
+	messages := ethr.NewBatch(count, 64*1024)
+	for i := range messages {
+		messages[i].OOB = make([]byte, ethr.SegmentOOBLen)
+	}
+	batch := ethr.NewBatchConn(udpConn)
+	datagrams := make([][]byte, 0, count)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		received, err := h.read(udpConn, batch, messages)
+		arrival := time.Now()
+		if err != nil {
+			h.logger.Debug("Error receiving data from UDP for bandwidth test: %v", err)
+			if received == 0 {
 				return
-			default:
-			}
-			var raddr net.Addr
-			var bytesRead int
-			bytesRead, raddr, err = udpConn.ReadFrom(readBuffer) // don't actually care about the packet just how many bytesRead we read 'n'
-			if err != nil {
-				h.logger.Debug("Error receiving data from UDP for bandwidth test: %v", err)
-				continue
 			}
+		}
 
-			if udpAddr, ok := raddr.(*net.UDPAddr); ok {
-				test, isNew := session.CreateOrGetTest(udpAddr.IP, uint16(udpAddr.Port), ethr.UDP, ethr.TestTypeServer, ethr.ClientParams{}, ServerAggregator, time.Second)
-				if isNew {
-					h.logger.Debug("Creating UDP test from server: %v, lastAccess: %v", udpAddr.String(), time.Now())
-					go test.Session.PollInactive(ctx, 100*time.Millisecond) // cleanup based on last access
+		for _, m := range messages[:received] {
+			// datagrams coalesced by UDP GRO are split back into what was on the wire
+			datagrams = ethr.AppendSegments(datagrams[:0], m.Buffers[0][:m.N], ethr.GROSegmentSize(m.OOB[:m.NN]))
+			count, bytes := 0, 0
+			var tracker *stats.StreamTracker
+			for _, d := range datagrams {
+				header, hasHeader := ethr.DecodeDatagramHeader(d)
+				if hasHeader && header.Type == ethr.DatagramData {
+					if tracker == nil {
+						tracker = h.streams.get(m.Addr.String())
+					}
+					tracker.Add(header.Sequence, header.SendTime, arrival)
 				}
+				if h.handleDatagram(ctx, udpConn, d, m.Addr) {
+					count++
+					bytes += len(d)
+				}
+			}
+			if count > 0 {
+				h.addBandwidth(ctx, m.Addr, count, bytes)
+			}
+		}
+	}
+}
+
+// read receives one datagram with ReadMsgUDP, or a batch of them with recvmmsg, and
+// returns how many messages were filled. With UDP GRO a message can hold several.
+func (h Handler) read(conn *net.UDPConn, batch ethr.BatchConn, messages []ipv4.Message) (int, error) {
+	if len(messages) > 1 {
+		return batch.ReadBatch(messages)
+	}
+	n, oobn, _, raddr, err := conn.ReadMsgUDP(messages[0].Buffers[0], messages[0].OOB)
+	if err != nil {
+		return 0, err
+	}
+	messages[0].N, messages[0].NN, messages[0].Addr = n, oobn, raddr
+	return 1, nil
+}
 
-				if test != nil {
-					test.AddIntermediateResult(session.TestResult{
-						Success: true,
-						Error:   nil,
-						Body: payloads.RawBandwidthPayload{
-							Bandwidth:        uint64(bytesRead),
-							PacketsPerSecond: 1,
-						},
-					})
+// handleDatagram answers the control datagrams of the tests and returns true for
+// those counted as bandwidth.
+func (h Handler) handleDatagram(ctx context.Context, udpConn *net.UDPConn, b []byte, raddr net.Addr) bool {
+	h.senders.touch(raddr.String())
+	if header, hasHeader := ethr.DecodeDatagramHeader(b); hasHeader {
+		switch header.Type {
+		case ethr.DatagramEcho:
+			// latency tests expect their datagrams to be echoed back as is
+			_, err := udpConn.WriteTo(b, raddr)
+			if err != nil {
+				h.logger.Debug("Error echoing UDP datagram to %v: %v", raddr, err)
+			}
+		case ethr.DatagramStart:
+			if start, ok := ethr.DecodeStreamStart(b); ok {
+				if udpAddr, ok := raddr.(*net.UDPAddr); ok {
+					h.handleStart(ctx, udpConn, udpAddr, start)
 				}
 			}
+		case ethr.DatagramDone:
+			h.sendReport(udpConn, raddr, header.Sequence)
+			if sent, ok := h.senders.finish(raddr.String()); ok {
+				h.sendDone(udpConn, raddr, sent)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// handleStart starts streaming to raddr once the client echoed its cookie, so a
+// spoofed DatagramStart can't make the server flood the address it names. The
+// cookie is no larger than the DatagramStart asking for it.
+func (h Handler) handleStart(ctx context.Context, udpConn *net.UDPConn, raddr *net.UDPAddr, start ethr.StreamStart) {
+	if !h.cookies.valid(raddr, start.Cookie) {
+		buff := make([]byte, ethr.DatagramHeaderLen)
+		ethr.DatagramHeader{Type: ethr.DatagramCookie, Sequence: h.cookies.of(raddr)}.Encode(buff)
+		_, err := udpConn.WriteTo(buff, raddr)
+		if err != nil {
+			h.logger.Debug("Error sending UDP stream cookie to %v: %v", raddr, err)
 		}
+		return
+	}
+	h.senders.start(ctx, h, udpConn, raddr, start)
+}
+
+// addBandwidth counts datagrams received from raddr.
+func (h Handler) addBandwidth(ctx context.Context, raddr net.Addr, count, bytes int) {
+	udpAddr, ok := raddr.(*net.UDPAddr)
+	if !ok {
+		return
+	}
+	test, isNew := session.CreateOrGetTest(udpAddr.IP, uint16(udpAddr.Port), ethr.UDP, ethr.TestTypeServer, ethr.ClientParams{}, ServerAggregator, time.Second)
+	if isNew {
+		h.logger.Debug("Creating UDP test from server: %v, lastAccess: %v", udpAddr.String(), time.Now())
+		go test.Session.PollInactive(ctx, 100*time.Millisecond) // cleanup based on last access
+	}
+
+	if test != nil {
+		test.AddIntermediateResult(session.TestResult{
+			Success: true,
+			Error:   nil,
+			Body: payloads.RawBandwidthPayload{
+				Bandwidth:        uint64(bytes),
+				PacketsPerSecond: uint64(count),
+			},
+		})
 	}
 }
 
-func ServerAggregator(seconds uint64, intermediateResults []session.TestResult) session.TestResult {
+func ServerAggregator(nanos uint64, intermediateResults []session.TestResult) session.TestResult {
 	totalBandwidth := uint64(0)
 	totalPackets := uint64(0)
 
@@ -83,8 +190,33 @@ func ServerAggregator(seconds uint64, intermediateResults []session.TestResult)
 		Success: true,
 		Error:   nil,
 		Body: payloads.ServerPayload{
-			PacketsPerSecond: totalPackets / seconds,
-			Bandwidth:        totalBandwidth / seconds,
+			PacketsPerSecond: 1e9 * totalPackets / nanos,
+			Bandwidth:        1e9 * totalBandwidth / nanos,
 		},
 	}
 }
+
+// sendReport answers a DatagramDone with the statistics of the stream, the client
+// may ask again if the report is lost.
+func (h Handler) sendReport(conn *net.UDPConn, raddr net.Addr, sent uint64) {
+	report := h.streams.get(raddr.String()).Report(sent)
+	h.logger.Debug("UDP stream from %v: sent %d, received %d, lost %d, out of order %d, duplicates %d, jitter %v",
+		raddr, report.Sent, report.Received, report.Lost, report.OutOfOrder, report.Duplicates, time.Duration(report.Jitter))
+
+	buff := make([]byte, ethr.StreamReportLen)
+	report.Encode(buff)
+	_, err := conn.WriteTo(buff, raddr)
+	if err != nil {
+		h.logger.Debug("Error sending UDP stream report to %v: %v", raddr, err)
+	}
+}
+
+// sendDone tells a client receiving a stream how many datagrams were sent.
+func (h Handler) sendDone(conn *net.UDPConn, raddr net.Addr, sent uint64) {
+	buff := make([]byte, ethr.DatagramHeaderLen)
+	ethr.DatagramHeader{Type: ethr.DatagramDone, Sequence: sent}.Encode(buff)
+	_, err := conn.WriteTo(buff, raddr)
+	if err != nil {
+		h.logger.Debug("Error sending UDP stream count to %v: %v", raddr, err)
+	}
+}
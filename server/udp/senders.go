@@ -0,0 +1,241 @@
+package udp
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+	"weavelab.xyz/ethr/stats"
+)
+
+// senderTimeout stops streams whose client went away without a DatagramDone.
+const senderTimeout = 2 * time.Second
+
+const maxDatagramSize = 65507
+
+// maxBatchSize is the most datagrams a single sendmmsg call takes on Linux.
+const maxBatchSize = 1024
+
+// maxSendRate caps the streams sent by the server, in bytes/s, unlimited ones
+// included.
+const maxSendRate = 10 * 1000 * 1000 * 1000 / 8
+
+// sender streams numbered datagrams to a client for reverse and bidirectional
+// tests until the client is done or stops sending keepalives.
+type sender struct {
+	lastAccess time.Time
+	sent       uint64
+	stop       chan struct{}
+	exited     chan struct{}
+	stopOnce   sync.Once
+}
+
+type senders struct {
+	sync.Mutex
+	senders map[string]*sender
+}
+
+func newSenders() *senders {
+	return &senders{
+		senders: make(map[string]*sender),
+	}
+}
+
+// start begins streaming to raddr unless a stream is already running, a stream
+// that stopped is replaced by the new one.
+func (s *senders) start(ctx context.Context, h Handler, conn *net.UDPConn, raddr *net.UDPAddr, params ethr.StreamStart) {
+	s.Lock()
+	defer s.Unlock()
+	if snd, ok := s.senders[raddr.String()]; ok && !snd.hasExited() {
+		return
+	}
+	for addr, snd := range s.senders {
+		// keep stopped senders around for a while, clients may repeat their DatagramDone
+		if time.Since(snd.lastAccess) > streamTimeout {
+			delete(s.senders, addr)
+		}
+	}
+	snd := &sender{
+		lastAccess: time.Now(),
+		stop:       make(chan struct{}),
+		exited:     make(chan struct{}),
+	}
+	s.senders[raddr.String()] = snd
+	go s.run(ctx, h, conn, raddr, params, snd)
+}
+
+// touch keeps the stream to addr going, any datagram from the client counts.
+func (s *senders) touch(addr string) {
+	s.Lock()
+	defer s.Unlock()
+	if snd, ok := s.senders[addr]; ok {
+		snd.lastAccess = time.Now()
+	}
+}
+
+// finish stops the stream to addr and returns the number of datagrams sent.
+func (s *senders) finish(addr string) (uint64, bool) {
+	s.Lock()
+	snd, ok := s.senders[addr]
+	s.Unlock()
+	if !ok {
+		return 0, false
+	}
+	snd.stopOnce.Do(func() { close(snd.stop) })
+	<-snd.exited
+	return snd.sent, true
+}
+
+func (snd *sender) hasExited() bool {
+	select {
+	case <-snd.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *senders) isActive(snd *sender) bool {
+	s.Lock()
+	defer s.Unlock()
+	return time.Since(snd.lastAccess) < senderTimeout
+}
+
+func (s *senders) run(ctx context.Context, h Handler, conn *net.UDPConn, raddr *net.UDPAddr, params ethr.StreamStart, snd *sender) {
+	defer close(snd.exited)
+
+	size := int(params.BufferSize)
+	if size < ethr.DatagramHeaderLen {
+		size = ethr.DatagramHeaderLen
+	} else if size > maxDatagramSize {
+		size = maxDatagramSize
+	}
+	h.logger.Debug("Starting UDP stream to %v, datagram size %d", raddr, size)
+
+	count := int(params.BatchSize)
+	if count < 1 {
+		count = 1
+	} else if count > maxBatchSize {
+		count = maxBatchSize
+	}
+	var segmented, segmentOOB []byte
+	if params.GSO {
+		count = ethr.MaxSegmentedBytes / size
+		if count > ethr.UDPMaxSegments {
+			count = ethr.UDPMaxSegments
+		} else if count < 1 {
+			count = 1
+		}
+		segmented = make([]byte, count*size)
+		segmentOOB = ethr.SegmentOOB(size)
+	}
+	messages := ethr.NewBatch(count, size)
+	buffers := make([][]byte, count)
+	for i := range messages {
+		buffers[i] = messages[i].Buffers[0]
+		if segmented != nil {
+			// the segments of a GSO send are consecutive
+			buffers[i] = segmented[i*size : (i+1)*size]
+		}
+		messages[i].Addr = raddr
+	}
+	filler, err := ethr.Content{Mode: params.Content}.NewFiller()
+	if err != nil {
+		h.logger.Error("Failed to fill the datagrams to %v: %v", raddr, err)
+		return
+	}
+	for _, b := range buffers {
+		filler.Fill(b[ethr.DatagramHeaderLen:])
+	}
+	batch := ethr.NewBatchConn(conn)
+	buffer := buffers[0]
+	rate := params.BwRate
+	if rate == 0 || rate > maxSendRate {
+		rate = maxSendRate
+	}
+	// all clients share the socket of the server, its pacing rate can't be per stream
+	limiter := stats.NewLimiter(ethr.ClientParams{
+		BufferSize: uint32(size),
+		BwRate:     rate,
+		Burst:      params.Burst,
+		Pacing:     ethr.PaceUser,
+	})
+	bytesToSend := limiter.LimitDatagram(len(buffer))
+	if bytesToSend < ethr.DatagramHeaderLen {
+		bytesToSend = ethr.DatagramHeaderLen
+	}
+	lastCheck := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-snd.stop:
+			return
+		default:
+		}
+		if time.Since(lastCheck) > 100*time.Millisecond {
+			if !s.isActive(snd) {
+				h.logger.Debug("Stopping UDP stream to inactive client %v", raddr)
+				return
+			}
+			lastCheck = time.Now()
+		}
+
+		batchCount := limiter.LimitBatch(count, bytesToSend)
+		if !limiter.Wait(snd.stop, batchCount*bytesToSend) {
+			return
+		}
+		if !filler.Static() {
+			for i := 0; i < batchCount; i++ {
+				filler.Fill(buffers[i][ethr.DatagramHeaderLen:bytesToSend])
+			}
+		}
+		now := time.Now().UnixNano()
+		for i := 0; i < batchCount; i++ {
+			ethr.DatagramHeader{Type: ethr.DatagramData, Sequence: snd.sent + uint64(i) + 1, SendTime: now}.Encode(buffers[i])
+		}
+		sent := 0
+		if batchCount == 1 || (segmented != nil && bytesToSend != size) {
+			n, err := conn.WriteToUDP(buffer[:bytesToSend], raddr)
+			if err == nil && n == bytesToSend {
+				sent = 1
+			}
+		} else if segmented != nil {
+			n, _, err := conn.WriteMsgUDP(segmented[:batchCount*size], segmentOOB, raddr)
+			if err != nil {
+				h.logger.Error("UDP GSO to %v failed, sending datagrams one at a time: %v", raddr, err)
+				segmented, count = nil, 1
+			} else if n == batchCount*size {
+				sent = batchCount
+			}
+		} else {
+			for i := 0; i < batchCount; i++ {
+				messages[i].Buffers[0] = buffers[i][:bytesToSend]
+			}
+			sent, _ = batch.WriteBatch(messages[:batchCount])
+		}
+		if sent == 0 {
+			continue
+		}
+		snd.sent += uint64(sent)
+		n := sent * bytesToSend
+
+		test, _ := session.CreateOrGetTest(raddr.IP, uint16(raddr.Port), ethr.UDP, ethr.TestTypeServer, ethr.ClientParams{}, ServerAggregator, time.Second)
+		if test != nil {
+			test.AddIntermediateResult(session.TestResult{
+				Success: true,
+				Error:   nil,
+				Body: payloads.RawBandwidthPayload{
+					Direction:        ethr.Downstream,
+					Bandwidth:        uint64(n),
+					PacketsPerSecond: uint64(sent),
+				},
+			})
+		}
+
+	}
+}
@@ -36,6 +36,15 @@ func Serve(ctx context.Context, cfg *server.Config, h Handler) error {
 	// reason is that for UDP, there is no connection, so all packets come
 	// on same CPU, so it isn't clear if there are any benefits to running
 	// more threads than NumCPU(). TODO: Evaluate this in future.
+	h.batchSize = cfg.BatchSize
+	h.cookies, err = newCookies()
+	if err != nil {
+		return fmt.Errorf("unable to create the secret of UDP stream cookies: %w", err)
+	}
+	err = ethr.EnableUDPGRO(l)
+	if err != nil {
+		h.logger.Debug("UDP GRO not enabled, datagrams are received one by one: %v", err)
+	}
 	for i := 0; i < runtime.NumCPU(); i++ {
 		go h.HandleConn(ctx, nil, l)
 	}
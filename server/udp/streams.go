@@ -0,0 +1,55 @@
+package udp
+
+import (
+	"sync"
+	"time"
+
+	"weavelab.xyz/ethr/stats"
+)
+
+// streamTimeout is how long the statistics of an idle stream are kept around for
+// clients retrying their DatagramDone.
+const streamTimeout = 10 * time.Second
+
+// streams tracks the sequence-numbered datagram streams of all clients by remote
+// address, each client thread sends from its own port. Handlers reading the
+// socket look trackers up concurrently and only lock the tracker of a stream.
+type streams struct {
+	sync.RWMutex
+	trackers map[string]*stats.StreamTracker
+}
+
+func newStreams() *streams {
+	return &streams{
+		trackers: make(map[string]*stats.StreamTracker),
+	}
+}
+
+func (s *streams) get(addr string) *stats.StreamTracker {
+	s.RLock()
+	tracker, ok := s.trackers[addr]
+	s.RUnlock()
+	if ok {
+		return tracker
+	}
+	s.Lock()
+	defer s.Unlock()
+	tracker, ok = s.trackers[addr]
+	if !ok {
+		s.removeInactive()
+		tracker = stats.NewStreamTracker()
+		s.trackers[addr] = tracker
+	}
+	return tracker
+}
+
+func (s *streams) removeInactive() {
+	for addr, tracker := range s.trackers {
+		tracker.Lock()
+		inactive := time.Since(tracker.LastAccess) > streamTimeout
+		tracker.Unlock()
+		if inactive {
+			delete(s.trackers, addr)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"io"
 	"net"
 	"os"
+	"sync/atomic"
 
 	"weavelab.xyz/ethr/ethr"
 )
@@ -26,23 +27,47 @@ func CreateSynMsg(testID ethr.TestID, clientParam ethr.ClientParams) (msg *ethr.
 	return
 }
 
+func CreateIntegrityMsg(verifier *ethr.StreamVerifier) (msg *ethr.Msg) {
+	msg = &ethr.Msg{Version: 0, Type: ethr.Integrity}
+	msg.Integrity = &ethr.MsgIntegrity{
+		Verified:        atomic.LoadUint64(&verifier.Verified),
+		Corrupted:       atomic.LoadUint64(&verifier.Corrupted),
+		FirstCorruption: atomic.LoadUint64(&verifier.FirstCorruption),
+	}
+	return
+}
+
 func (s Session) HandshakeWithServer(test *Test, conn net.Conn) error {
-	msg := CreateSynMsg(test.ID, test.ClientParam)
+	_, err := s.HandshakeWithServerStream(test, 0, conn)
+	return err
+}
+
+// HandshakeWithServerStream sends the params of one stream of the test, as
+// streams can use different congestion control algorithms, and returns the
+// socket options the server end of the stream was granted.
+func (s *Session) HandshakeWithServerStream(test *Test, stream int, conn net.Conn) (ethr.StreamOptions, error) {
+	msg := CreateSynMsg(test.ID, test.ClientParam.ForStream(stream))
 	err := s.Send(conn, msg)
 	if err != nil {
-		return fmt.Errorf("failed to send SYN message: %w", err)
+		return ethr.StreamOptions{}, fmt.Errorf("failed to send SYN message: %w", err)
 	}
 	resp, err := s.Receive(conn)
 	if err != nil {
-		return err
+		return ethr.StreamOptions{}, err
+	}
+	if resp.Type != ethr.Ack || resp.Ack == nil {
+		return ethr.StreamOptions{}, fmt.Errorf("failed to receive ACK message: %w", os.ErrInvalid)
 	}
-	if resp.Type != ethr.Ack {
-		return fmt.Errorf("failed to receive ACK message: %w", os.ErrInvalid)
+	if resp.Ack.Error != "" {
+		return ethr.StreamOptions{}, fmt.Errorf("server refused the stream: %s", resp.Ack.Error)
 	}
-	return nil
+	return resp.Ack.StreamOptions, nil
 }
 
-func (s Session) HandshakeWithClient(conn net.Conn) (testID ethr.TestID, clientParam ethr.ClientParams, err error) {
+// HandshakeWithClient receives the params of a stream and has accept prepare the
+// stream for them before answering, the client learns why accept failed or the
+// socket options it granted from the ACK.
+func (s Session) HandshakeWithClient(conn net.Conn, accept func(ethr.TestID, ethr.ClientParams) (ethr.StreamOptions, error)) (testID ethr.TestID, clientParam ethr.ClientParams, err error) {
 	msg, err := s.Receive(conn)
 	if err != nil {
 		return
@@ -54,6 +79,12 @@ func (s Session) HandshakeWithClient(conn net.Conn) (testID ethr.TestID, clientP
 	testID = msg.Syn.TestID
 	clientParam = msg.Syn.ClientParam
 	ack := CreateAckMsg()
+	ack.Ack.StreamOptions, err = accept(testID, clientParam)
+	if err != nil {
+		ack.Ack.Error = err.Error()
+		_ = s.Send(conn, ack)
+		return
+	}
 	err = s.Send(conn, ack)
 	return
 }
@@ -2,17 +2,25 @@ package payloads
 
 import (
 	"fmt"
+	"time"
 
+	"weavelab.xyz/ethr/ethr"
 	"weavelab.xyz/ethr/ui"
 )
 
 type RawBandwidthPayload struct {
 	ConnectionID     string
+	Direction        ethr.Direction
 	Bandwidth        uint64
 	PacketsPerSecond uint64
+	// latest sample of TCP connections, nil when not available
+	TCPInfo *TCPInfo
 }
 
 func (p RawBandwidthPayload) String() string {
+	if p.TCPInfo != nil {
+		return fmt.Sprintf("id: %s, bandwidth: %s pkt/s: %s %s", p.ConnectionID, ui.BytesToRate(p.Bandwidth), ui.PpsToString(p.PacketsPerSecond), p.TCPInfo)
+	}
 	return fmt.Sprintf("id: %s, bandwidth: %s pkt/s: %s", p.ConnectionID, ui.BytesToRate(p.Bandwidth), ui.PpsToString(p.PacketsPerSecond))
 }
 
@@ -20,8 +28,76 @@ type BandwidthPayload struct {
 	TotalBandwidth        uint64
 	TotalPacketsPerSecond uint64
 	ConnectionBandwidths  []RawBandwidthPayload
+	// totals of each direction with traffic, connection ids are empty
+	DirectionBandwidths []RawBandwidthPayload
+	// average rate the traffic profile aimed at in each direction, 0 without one
+	TargetRate uint64
 }
 
 func (p BandwidthPayload) String() string {
+	if p.TargetRate > 0 {
+		return fmt.Sprintf("connections: %d, bandwidth: %s pkt/s: %s target: %s", len(p.ConnectionBandwidths), ui.BytesToRate(p.TotalBandwidth), ui.PpsToString(p.TotalPacketsPerSecond), ui.BytesToRate(p.TargetRate))
+	}
 	return fmt.Sprintf("connections: %d, bandwidth: %s pkt/s: %s", len(p.ConnectionBandwidths), ui.BytesToRate(p.TotalBandwidth), ui.PpsToString(p.TotalPacketsPerSecond))
 }
+
+// TargetRatePayload is a sample of the rate in bytes/s the traffic profile of a
+// test aims at.
+type TargetRatePayload struct {
+	Rate uint64
+}
+
+// DatagramStatsPayload is the receiver's view of a sequence-numbered UDP stream.
+type DatagramStatsPayload struct {
+	ConnectionID string
+	Direction    ethr.Direction
+	Sent         uint64
+	Received     uint64
+	Lost         uint64
+	OutOfOrder   uint64
+	Duplicates   uint64
+	Jitter       time.Duration
+}
+
+func (p DatagramStatsPayload) LossPercent() float64 {
+	if p.Sent == 0 {
+		return 0
+	}
+	return 100 * float64(p.Lost) / float64(p.Sent)
+}
+
+func (p DatagramStatsPayload) String() string {
+	return fmt.Sprintf("id: %s %s, sent: %d received: %d lost: %d (%.2f%%) out of order: %d duplicates: %d jitter: %s",
+		p.ConnectionID, p.Direction, p.Sent, p.Received, p.Lost, p.LossPercent(), p.OutOfOrder, p.Duplicates, ui.DurationToString(p.Jitter))
+}
+
+// CPUCostPayload is the CPU time the client process spent moving the bytes of a
+// TCP bandwidth test, ZeroCopied and Copied count MSG_ZEROCOPY sends.
+type CPUCostPayload struct {
+	Mode       ethr.SendMode
+	Sent       uint64
+	Received   uint64
+	User       time.Duration
+	System     time.Duration
+	ZeroCopied uint64
+	Copied     uint64
+}
+
+// PerGigabit is the CPU time spent per gigabit sent or received.
+func (p CPUCostPayload) PerGigabit() time.Duration {
+	bits := 8 * (p.Sent + p.Received)
+	if bits == 0 {
+		return 0
+	}
+	return time.Duration(float64(p.User+p.System) * 1e9 / float64(bits))
+}
+
+func (p CPUCostPayload) String() string {
+	s := fmt.Sprintf("mode: %s sent: %sB received: %sB cpu user: %s system: %s per gigabit: %s",
+		p.Mode, ui.NumberToUnit(p.Sent), ui.NumberToUnit(p.Received),
+		ui.DurationToString(p.User), ui.DurationToString(p.System), ui.DurationToString(p.PerGigabit()))
+	if p.Mode == ethr.SendMsgZeroCopy {
+		s += fmt.Sprintf(" zero-copied sends: %d copied sends: %d", p.ZeroCopied, p.Copied)
+	}
+	return s
+}
@@ -1,11 +1,100 @@
 package payloads
 
-import "fmt"
+import (
+	"fmt"
+	"time"
 
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/ui"
+)
+
+// ConnectionsPerSecondPayload is the connections, or HTTP requests, per second.
+// Failed is the HTTP requests that failed per second.
 type ConnectionsPerSecondPayload struct {
 	Connections uint64
+	Failed      uint64
 }
 
 func (p ConnectionsPerSecondPayload) String() string {
+	if p.Failed > 0 {
+		return fmt.Sprintf("connections: %d failed: %d", p.Connections, p.Failed)
+	}
 	return fmt.Sprintf("connections: %d", p.Connections)
 }
+
+// RawConnectPayload is an attempt to open a connection of a max connections
+// test, or the loss of a held one.
+type RawConnectPayload struct {
+	Latency time.Duration
+	Failed  bool
+	Failure ethr.ConnectFailure
+	Dropped bool
+}
+
+// OpenConnectionsPayload is a sample of the connections a max connections test
+// holds.
+type OpenConnectionsPayload struct {
+	Open uint64
+}
+
+// MaxConnectionsPayload is an interval of a max connections test, Latency is
+// the time to connect of the connections opened in it.
+type MaxConnectionsPayload struct {
+	Open     uint64
+	Opened   uint64
+	Failed   uint64
+	Dropped  uint64
+	Failures [ethr.ConnectFailureCount]uint64
+	Latency  LatencyPayload
+}
+
+func (p MaxConnectionsPayload) String() string {
+	return fmt.Sprintf("open: %d opened: %d failed: %d dropped: %d connect latency: %s", p.Open, p.Opened, p.Failed, p.Dropped, p.Latency)
+}
+
+// ConnectLevelPayload is the time to connect of the connections a max
+// connections test opened while holding up to UpTo connections.
+type ConnectLevelPayload struct {
+	UpTo    uint64
+	Opened  uint64
+	Latency LatencyPayload
+}
+
+func (p ConnectLevelPayload) String() string {
+	return fmt.Sprintf("up to %d connections, opened: %d connect latency: %s", p.UpTo, p.Opened, p.Latency)
+}
+
+// MaxConnectionsSummaryPayload is what a max connections test reached. Failures
+// started at FirstFailureOpen held connections, after FirstFailureAfter.
+type MaxConnectionsSummaryPayload struct {
+	Peak              uint64
+	Failures          [ethr.ConnectFailureCount]uint64
+	Dropped           uint64
+	FirstFailure      ethr.ConnectFailure
+	FirstFailureOpen  uint64
+	FirstFailureAfter time.Duration
+	Levels            []ConnectLevelPayload
+}
+
+// Failed tells if opening any of the connections failed.
+func (p MaxConnectionsSummaryPayload) Failed() bool {
+	for _, f := range p.Failures {
+		if f > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (p MaxConnectionsSummaryPayload) String() string {
+	s := fmt.Sprintf("peak: %d connections dropped: %d", p.Peak, p.Dropped)
+	if p.Failed() {
+		s += fmt.Sprintf(" failures started at %d connections after %s: %s, failed:", p.FirstFailureOpen, ui.DurationToString(p.FirstFailureAfter), p.FirstFailure)
+		for f, n := range p.Failures {
+			if n > 0 {
+				s += fmt.Sprintf(" %s: %d", ethr.ConnectFailure(f), n)
+			}
+		}
+	}
+	return s
+}
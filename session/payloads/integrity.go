@@ -0,0 +1,26 @@
+package payloads
+
+import (
+	"fmt"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/ui"
+)
+
+// IntegrityPayload is what the receiver of a stream verified of it, Corrupted
+// bytes didn't match the stream of the sender, the first at FirstCorruption.
+type IntegrityPayload struct {
+	ConnectionID    string
+	Direction       ethr.Direction
+	Verified        uint64
+	Corrupted       uint64
+	FirstCorruption uint64
+}
+
+func (p IntegrityPayload) String() string {
+	if p.Corrupted > 0 {
+		return fmt.Sprintf("connection: %s direction: %s verified: %sB corrupted: %d bytes, the first at offset %d",
+			p.ConnectionID, p.Direction, ui.NumberToUnit(p.Verified), p.Corrupted, p.FirstCorruption)
+	}
+	return fmt.Sprintf("connection: %s direction: %s verified: %sB intact", p.ConnectionID, p.Direction, ui.NumberToUnit(p.Verified))
+}
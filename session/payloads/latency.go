@@ -11,6 +11,7 @@ import (
 
 type RawLatencies struct {
 	Latencies []time.Duration
+	TCPInfo   *TCPInfo
 }
 
 type LatencyPayload struct {
@@ -25,6 +26,8 @@ type LatencyPayload struct {
 	P99    time.Duration
 	P999   time.Duration
 	P9999  time.Duration
+	// latest sample of TCP connections, nil when not available
+	TCPInfo *TCPInfo
 }
 
 func (p LatencyPayload) String() string {
@@ -86,3 +89,25 @@ func NewLatencies(latencies []time.Duration) LatencyPayload {
 		P9999:  latencies[uint64(((float64(rttCountFixed)*99.9)/100)-1)],
 	}
 }
+
+type RawUDPLatencies struct {
+	Latencies []time.Duration
+	Sent      uint64
+	TimedOut  uint64
+	Late      uint64
+}
+
+type UDPLatencyPayload struct {
+	Latency LatencyPayload
+	Sent    uint64
+	Lost    uint64
+	Late    uint64
+}
+
+func (p UDPLatencyPayload) String() string {
+	loss := 0.0
+	if p.Sent > 0 {
+		loss = 100 * float64(p.Lost) / float64(p.Sent)
+	}
+	return fmt.Sprintf("%s %8.2f%% %9d", p.Latency, loss, p.Late)
+}
@@ -0,0 +1,77 @@
+package payloads
+
+import (
+	"fmt"
+	"time"
+
+	"weavelab.xyz/ethr/ui"
+)
+
+type LoadPhase int
+
+const (
+	LoadPhaseIdle LoadPhase = iota
+	LoadPhaseLoaded
+	LoadPhaseRecovery
+)
+
+func (p LoadPhase) String() string {
+	switch p {
+	case LoadPhaseIdle:
+		return "Idle"
+	case LoadPhaseLoaded:
+		return "Loaded"
+	}
+	return "Recovery"
+}
+
+// LoadedLatencySample is the ping latency of one interval, Bandwidth is zero
+// outside of the loaded phase.
+type LoadedLatencySample struct {
+	Phase     LoadPhase
+	Ping      PingPayload
+	Bandwidth uint64
+}
+
+func (p LoadedLatencySample) String() string {
+	return fmt.Sprintf("%s: %s bandwidth: %s", p.Phase, p.Ping, ui.BytesToRate(p.Bandwidth))
+}
+
+// LoadedLatencyPayload compares the latency before, during and after the
+// bandwidth test, Increase is the growth of the average latency under load.
+type LoadedLatencyPayload struct {
+	Idle      PingPayload
+	Loaded    PingPayload
+	Recovery  PingPayload
+	Bandwidth uint64
+	Increase  time.Duration
+	Grade     string
+}
+
+func (p LoadedLatencyPayload) String() string {
+	return fmt.Sprintf("idle latency: %s loaded latency: %s recovery latency: %s bandwidth: %s increase: %s grade: %s",
+		ui.DurationToString(p.Idle.Latency.Avg),
+		ui.DurationToString(p.Loaded.Latency.Avg),
+		ui.DurationToString(p.Recovery.Latency.Avg),
+		ui.BytesToRate(p.Bandwidth),
+		ui.DurationToString(p.Increase),
+		p.Grade)
+}
+
+// BufferbloatGrade grades the latency increase under load, the same way as the
+// common bufferbloat tests do.
+func BufferbloatGrade(increase time.Duration) string {
+	switch {
+	case increase < 5*time.Millisecond:
+		return "A+"
+	case increase < 30*time.Millisecond:
+		return "A"
+	case increase < 60*time.Millisecond:
+		return "B"
+	case increase < 200*time.Millisecond:
+		return "C"
+	case increase < 400*time.Millisecond:
+		return "D"
+	}
+	return "F"
+}
@@ -0,0 +1,65 @@
+package payloads
+
+import (
+	"fmt"
+	"net"
+)
+
+type MTUProbeResult int
+
+const (
+	MTUProbeFits MTUProbeResult = iota
+	MTUProbeTooBig
+	MTUProbeLost
+)
+
+func (r MTUProbeResult) String() string {
+	switch r {
+	case MTUProbeFits:
+		return "fits"
+	case MTUProbeTooBig:
+		return "too big"
+	}
+	return "no answer"
+}
+
+// MTUProbe is a single packet size tried while searching the path MTU, Peer is
+// the router answering with Fragmentation Needed or Packet Too Big.
+type MTUProbe struct {
+	Size       int
+	Result     MTUProbeResult
+	Peer       net.Addr
+	NextHopMTU int
+}
+
+func (p MTUProbe) String() string {
+	if p.Peer == nil {
+		return fmt.Sprintf("%5d bytes: %s", p.Size, p.Result)
+	}
+	if p.NextHopMTU > 0 {
+		return fmt.Sprintf("%5d bytes: %s from %s, next hop MTU %d", p.Size, p.Result, p.Peer, p.NextHopMTU)
+	}
+	return fmt.Sprintf("%5d bytes: %s from %s", p.Size, p.Result, p.Peer)
+}
+
+// MTUPayload is the path MTU, LimitingHop is nil when the local interface limits
+// it. BlackHole means bigger packets are dropped without an ICMP error.
+type MTUPayload struct {
+	MTU         int
+	LimitingHop net.Addr
+	HopNumber   int
+	BlackHole   bool
+}
+
+func (p MTUPayload) String() string {
+	if p.LimitingHop == nil {
+		if p.BlackHole {
+			return fmt.Sprintf("path MTU: %d, bigger packets are dropped without ICMP errors (black hole)", p.MTU)
+		}
+		return fmt.Sprintf("path MTU: %d, limited by the local interface", p.MTU)
+	}
+	if p.BlackHole {
+		return fmt.Sprintf("path MTU: %d, bigger packets are dropped without ICMP errors after hop %d (%s) (black hole)", p.MTU, p.HopNumber, p.LimitingHop)
+	}
+	return fmt.Sprintf("path MTU: %d, limited by hop %d (%s)", p.MTU, p.HopNumber, p.LimitingHop)
+}
@@ -0,0 +1,41 @@
+package payloads
+
+import (
+	"fmt"
+	"time"
+
+	"weavelab.xyz/ethr/ui"
+)
+
+// SizeBucketPayload is what the client sent in messages of up to UpTo bytes.
+// SendTime is the time spent in writes of bandwidth tests, Latency is only set
+// for latency tests.
+type SizeBucketPayload struct {
+	UpTo     uint32
+	Messages uint64
+	Bytes    uint64
+	SendTime time.Duration
+	Latency  *LatencyPayload
+}
+
+// WriteRate is the bytes per second the writes of the bucket moved.
+func (p SizeBucketPayload) WriteRate() uint64 {
+	if p.SendTime <= 0 {
+		return 0
+	}
+	return uint64(float64(p.Bytes) * 1e9 / float64(p.SendTime))
+}
+
+func (p SizeBucketPayload) String() string {
+	s := fmt.Sprintf("size: <=%dB messages: %d bytes: %sB", p.UpTo, p.Messages, ui.NumberToUnit(p.Bytes))
+	if p.Latency != nil {
+		return s + " latency: " + p.Latency.String()
+	}
+	return s + " write rate: " + ui.BytesToRate(p.WriteRate())
+}
+
+// SizeBucketsPayload breaks the results of a test sending messages of varying
+// sizes out by size, the buckets are sorted by size.
+type SizeBucketsPayload struct {
+	Buckets []SizeBucketPayload
+}
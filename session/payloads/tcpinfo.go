@@ -0,0 +1,45 @@
+package payloads
+
+import (
+	"fmt"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/ui"
+)
+
+// TCPInfo is the kernel's view of a TCP connection, only sampled on Linux.
+// The congestion and send/receive windows are in bytes, rates in bytes/s and
+// Retransmits counts every segment retransmitted on the connection so far.
+type TCPInfo struct {
+	CongestionWindow uint64
+	SmoothedRTT      time.Duration
+	Retransmits      uint32
+	DeliveryRate     uint64
+	PacingRate       uint64
+	SendWindow       uint32
+	ReceiveWindow    uint32
+}
+
+func (i TCPInfo) String() string {
+	return fmt.Sprintf("cwnd: %sB srtt: %s retrans: %d delivery: %s pacing: %s snd_wnd: %sB rcv_wnd: %sB",
+		ui.NumberToUnit(i.CongestionWindow),
+		ui.DurationToString(i.SmoothedRTT),
+		i.Retransmits,
+		ui.BytesToRate(i.DeliveryRate),
+		ui.BytesToRate(i.PacingRate),
+		ui.NumberToUnit(uint64(i.SendWindow)),
+		ui.NumberToUnit(uint64(i.ReceiveWindow)))
+}
+
+// StreamOptionsPayload is the socket options the kernel granted a stream, Server
+// those of the server end.
+type StreamOptionsPayload struct {
+	ConnectionID string
+	Options      ethr.StreamOptions
+	Server       ethr.StreamOptions
+}
+
+func (p StreamOptionsPayload) String() string {
+	return fmt.Sprintf("id: %s, %s, server cc: %s", p.ConnectionID, p.Options, p.Server.CCString())
+}
@@ -0,0 +1,44 @@
+package payloads
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"weavelab.xyz/ethr/ui"
+)
+
+type RawTLSHandshakes struct {
+	Profile string
+	Full    []time.Duration
+	Resumed []time.Duration
+}
+
+type TLSHandshakePayload struct {
+	Profile string
+	Full    LatencyPayload
+	Resumed LatencyPayload
+}
+
+func (p TLSHandshakePayload) String() string {
+	return fmt.Sprintf("%-44s %9s %9s %9s %9s %9s %9s",
+		p.Profile,
+		ui.DurationToString(p.Full.Avg),
+		ui.DurationToString(p.Full.P50),
+		ui.DurationToString(p.Full.P99),
+		ui.DurationToString(p.Resumed.Avg),
+		ui.DurationToString(p.Resumed.P50),
+		ui.DurationToString(p.Resumed.P99))
+}
+
+type TLSPayload struct {
+	Handshakes []TLSHandshakePayload
+}
+
+func (p TLSPayload) String() string {
+	profiles := make([]string, 0, len(p.Handshakes))
+	for _, h := range p.Handshakes {
+		profiles = append(profiles, fmt.Sprintf("%s full: %s resumed: %s", h.Profile, ui.DurationToString(h.Full.Avg), ui.DurationToString(h.Resumed.Avg)))
+	}
+	return strings.Join(profiles, ", ")
+}
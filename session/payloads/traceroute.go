@@ -3,6 +3,7 @@ package payloads
 import (
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,6 +12,8 @@ import (
 
 type TraceRoutePayload struct {
 	Hops []NetworkHop
+	// Multipath holds every router seen at each hop when probes vary their flow
+	Multipath []MultipathHop
 }
 
 func (p TraceRoutePayload) String() string {
@@ -18,9 +21,50 @@ func (p TraceRoutePayload) String() string {
 	for _, hop := range p.Hops {
 		parts = append(parts, hop.String())
 	}
+	if len(p.Multipath) > 0 {
+		parts = append(parts, "Paths:")
+		for _, hop := range p.Multipath {
+			parts = append(parts, hop.String())
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// MultipathHop lists the routers answering the probes of different flows at
+// the same hop, more than one router means the flows are load balanced.
+type MultipathHop struct {
+	HopNumber int
+	Routers   []MultipathRouter
+}
+
+func (h MultipathHop) String() string {
+	parts := make([]string, 0, len(h.Routers))
+	for _, r := range h.Routers {
+		parts = append(parts, r.String())
+	}
 	return strings.Join(parts, "\n")
 }
 
+// MultipathRouter is a node of the multipath graph, Next links it to the routers
+// its flows reach at the following hop.
+type MultipathRouter struct {
+	NetworkHop
+	Flows []int
+	Next  []string
+}
+
+func (r MultipathRouter) String() string {
+	flows := make([]string, 0, len(r.Flows))
+	for _, f := range r.Flows {
+		flows = append(flows, strconv.Itoa(f))
+	}
+	next := ""
+	if len(r.Next) > 0 {
+		next = " -> " + strings.Join(r.Next, ", ")
+	}
+	return fmt.Sprintf("%s\tflows: %s%s", r.NetworkHop, strings.Join(flows, ","), next)
+}
+
 type NetworkHop struct {
 	HopNumber int
 	Addr      net.Addr
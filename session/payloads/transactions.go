@@ -0,0 +1,30 @@
+package payloads
+
+import (
+	"fmt"
+	"time"
+
+	"weavelab.xyz/ethr/ui"
+)
+
+// RawTransaction is one request and its response, for TCP_CRR tests Latency
+// includes opening the connection. Failed TCP_CRR transactions have no Latency.
+type RawTransaction struct {
+	Latency time.Duration
+	Failed  bool
+}
+
+// TransactionsPayload is the request/response transactions of all connections
+// of a test over an interval.
+type TransactionsPayload struct {
+	TransactionsPerSecond uint64
+	FailedPerSecond       uint64
+	Latency               LatencyPayload
+}
+
+func (p TransactionsPayload) String() string {
+	if p.FailedPerSecond > 0 {
+		return fmt.Sprintf("trans/s: %s failed/s: %s latency: %s", ui.CpsToString(p.TransactionsPerSecond), ui.CpsToString(p.FailedPerSecond), p.Latency)
+	}
+	return fmt.Sprintf("trans/s: %s latency: %s", ui.CpsToString(p.TransactionsPerSecond), p.Latency)
+}
@@ -20,6 +20,7 @@ var Logger ethr.Logger
 
 var sessions = make(map[string]*Session)
 var sessionLock sync.RWMutex
+var createLock sync.Mutex
 
 func GetSessions() []Session {
 	out := make([]Session, 0, len(sessions))
@@ -79,6 +80,10 @@ func (s *Session) PollInactive(ctx context.Context, gap time.Duration) {
 }
 
 func CreateOrGetTest(rIP net.IP, rPort uint16, protocol ethr.Protocol, testType ethr.TestType, params ethr.ClientParams, aggregator ResultAggregator, publishInterval time.Duration) (*Test, bool) {
+	// connections are dispatched concurrently, make sure only one of them creates the test
+	createLock.Lock()
+	defer createLock.Unlock()
+
 	isNew := false
 	session := getOrCreateSession(rIP)
 	test := session.getTest(protocol, testType)
@@ -23,11 +23,20 @@ type Test struct {
 	Done        chan struct{}
 	LastAccess  time.Time
 
+	// Wrapup is waited on by the client after the test is done, tests add to it
+	// while they collect results that are only known at the end, e.g. from the server.
+	Wrapup sync.WaitGroup
+
+	// both the publisher and the client close Results once the test is done,
+	// results added later are dropped
+	resultsClosed bool
+
 	resultLock          sync.Mutex
 	publishInterval     time.Duration
 	intermediateResults []TestResult
 	aggregator          ResultAggregator
 	latestResult        *TestResult
+	summaries           []TestResult
 }
 
 type TestResult struct {
@@ -81,10 +90,7 @@ func (t *Test) republishAll(ticker *time.Ticker) {
 	doRepublish := func() {
 		t.resultLock.Lock()
 		for _, r := range t.intermediateResults {
-			select {
-			case t.Results <- r:
-			default:
-			}
+			t.publish(r)
 			t.latestResult = &r
 		}
 		if len(t.intermediateResults) > 0 {
@@ -99,7 +105,7 @@ func (t *Test) republishAll(ticker *time.Ticker) {
 		select {
 		case <-t.Done:
 			doRepublish()
-			close(t.Results)
+			t.CloseResults()
 			return
 		default:
 			doRepublish()
@@ -124,12 +130,8 @@ func (t *Test) republishAggregates(ticker *time.Ticker) {
 		r := t.aggregator(ns, t.intermediateResults)
 		t.intermediateResults = make([]TestResult, 0, cap(t.intermediateResults))
 		t.latestResult = &r
+		t.publish(r)
 		t.resultLock.Unlock()
-
-		select {
-		case t.Results <- r:
-		default:
-		}
 		return true
 	}
 
@@ -138,7 +140,7 @@ func (t *Test) republishAggregates(ticker *time.Ticker) {
 		case <-t.Done:
 			// cleanup any unpublished results
 			_ = doAggregate(start)
-			close(t.Results)
+			t.CloseResults()
 			return
 		default:
 			republished := doAggregate(start)
@@ -150,6 +152,27 @@ func (t *Test) republishAggregates(ticker *time.Ticker) {
 	}
 }
 
+// CloseResults closes the Results channel, only the first call does.
+func (t *Test) CloseResults() {
+	t.resultLock.Lock()
+	defer t.resultLock.Unlock()
+	if !t.resultsClosed {
+		t.resultsClosed = true
+		close(t.Results)
+	}
+}
+
+// publish sends r to the readers of Results without waiting, resultLock must be held.
+func (t *Test) publish(r TestResult) {
+	if t.resultsClosed {
+		return
+	}
+	select {
+	case t.Results <- r:
+	default:
+	}
+}
+
 func (t *Test) Terminate() {
 	close(t.Done)
 	t.IsActive = false
@@ -172,9 +195,18 @@ func (t *Test) AddDirectResult(r TestResult) {
 	t.resultLock.Lock()
 	defer t.resultLock.Unlock()
 	t.latestResult = &r
-	select {
-	case t.Results <- r:
-	default:
-	}
+	t.publish(r)
+}
 
+// AddSummary records a final result, summaries are printed once the test is done.
+func (t *Test) AddSummary(r TestResult) {
+	t.resultLock.Lock()
+	defer t.resultLock.Unlock()
+	t.summaries = append(t.summaries, r)
+}
+
+func (t *Test) Summaries() []TestResult {
+	t.resultLock.Lock()
+	defer t.resultLock.Unlock()
+	return t.summaries
 }
@@ -0,0 +1,18 @@
+// +build linux darwin
+
+package stats
+
+import (
+	"syscall"
+	"time"
+)
+
+// ProcessCPUTime returns the user and system CPU time the process used so far.
+func ProcessCPUTime() (user, system time.Duration, err error) {
+	var ru syscall.Rusage
+	err = syscall.Getrusage(syscall.RUSAGE_SELF, &ru)
+	if err != nil {
+		return 0, 0, err
+	}
+	return time.Duration(ru.Utime.Nano()), time.Duration(ru.Stime.Nano()), nil
+}
@@ -0,0 +1,27 @@
+// +build windows
+
+package stats
+
+import (
+	"syscall"
+	"time"
+)
+
+// ProcessCPUTime returns the user and system CPU time the process used so far.
+func ProcessCPUTime() (user, system time.Duration, err error) {
+	h, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return 0, 0, err
+	}
+	var creation, exit, kernel, usr syscall.Filetime
+	err = syscall.GetProcessTimes(h, &creation, &exit, &kernel, &usr)
+	if err != nil {
+		return 0, 0, err
+	}
+	// FILETIMEs count 100ns intervals
+	return filetimeToDuration(usr), filetimeToDuration(kernel), nil
+}
+
+func filetimeToDuration(ft syscall.Filetime) time.Duration {
+	return time.Duration(uint64(ft.HighDateTime)<<32|uint64(ft.LowDateTime)) * 100
+}
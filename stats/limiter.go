@@ -0,0 +1,189 @@
+package stats
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+)
+
+// offPoll is how often limiters check if a traffic profile is sending again.
+const offPoll = time.Millisecond
+
+// rateSlice is how long the rate of a traffic profile is taken as constant, waits
+// check it again that often and Limit keeps sends within that much of it.
+const rateSlice = 10 * time.Millisecond
+
+// Limiter is a token bucket limiting sends to a rate in bytes/s with bursts of
+// up to burst bytes and one more send, the bucket starts full. It refills at the
+// rate of the traffic profile of the test at the time. All streams of a test can share
+// one limiter. A nil Limiter doesn't limit.
+type Limiter struct {
+	mu       sync.Mutex
+	rate     uint64
+	burst    uint64
+	profile  ethr.TrafficProfile
+	start    time.Time
+	tokens   float64 // bytes in the bucket, below 0 when it is in debt
+	last     time.Time
+	lastRate uint64
+	random   *rand.Rand
+}
+
+// NewLimiter returns the limiter of streams with the given params, nil when
+// they are unlimited or paced by the kernel. A Burst of 0 holds a millisecond of
+// the rate, at least one buffer.
+func NewLimiter(params ethr.ClientParams) *Limiter {
+	if params.BwRate == 0 || params.Pacing == ethr.PaceKernel {
+		return nil
+	}
+	burst := uint64(params.Burst)
+	if burst == 0 {
+		burst = params.BwRate / 1000
+		if burst < uint64(params.BufferSize) {
+			burst = uint64(params.BufferSize)
+		}
+	}
+	now := time.Now()
+	l := &Limiter{
+		rate:    params.BwRate,
+		burst:   burst,
+		profile: params.Profile,
+		start:   now,
+		tokens:  float64(burst),
+		last:    now,
+	}
+	l.lastRate = l.profile.RateAt(0, l.rate)
+	if params.Profile.Kind == ethr.ProfilePoisson {
+		l.random = rand.New(rand.NewSource(now.UnixNano()))
+	}
+	return l
+}
+
+// refill adds what the bucket gained since the last refill, at the mean of the
+// rates then and now, and returns the rate now. The lock must be held.
+func (l *Limiter) refill(now time.Time) uint64 {
+	rate := l.profile.RateAt(now.Sub(l.start), l.rate)
+	if now.After(l.last) {
+		l.tokens += float64(l.lastRate+rate) / 2 * now.Sub(l.last).Seconds()
+		if l.tokens > float64(l.burst) {
+			l.tokens = float64(l.burst)
+		}
+		l.last = now
+	}
+	l.lastRate = rate
+	return rate
+}
+
+// Wait takes n bytes out of the bucket, blocking while it is in debt. Taking
+// more than the bucket holds leaves it in debt, the next sends wait until it is
+// paid back at the rate of the time. Wait returns false without taking the bytes
+// when done is closed first.
+func (l *Limiter) Wait(done <-chan struct{}, n int) bool {
+	if l == nil || n <= 0 {
+		return true
+	}
+	need := float64(n)
+	if l.random != nil {
+		l.mu.Lock()
+		need *= l.random.ExpFloat64()
+		l.mu.Unlock()
+	}
+	for {
+		l.mu.Lock()
+		rate := l.refill(time.Now())
+		if l.tokens >= 0 {
+			l.tokens -= need
+			l.mu.Unlock()
+			return true
+		}
+		wait := offPoll
+		if rate > 0 {
+			wait = time.Duration(-l.tokens / float64(rate) * float64(time.Second))
+			if wait > rateSlice && l.profile.Kind != ethr.ProfileConstant {
+				// the rate changes while waiting
+				wait = rateSlice
+			}
+		}
+		l.mu.Unlock()
+		timer := time.NewTimer(wait)
+		select {
+		case <-done:
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// maxSend returns how many bytes one send can take: the burst, or what the
+// current rate sends in rateSlice when that is smaller so slow rates don't wait
+// long between sends. It is never below 1.
+func (l *Limiter) maxSend() uint64 {
+	rate := l.profile.RateAt(time.Since(l.start), l.rate)
+	n := l.burst
+	if slice := uint64(float64(rate) * rateSlice.Seconds()); rate > 0 && slice < n {
+		n = slice
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// Limit returns n, or less when the burst or the current rate allow less, see
+// maxSend. Streams call it for every send.
+func (l *Limiter) Limit(n int) int {
+	if l == nil {
+		return n
+	}
+	if max := l.maxSend(); uint64(n) > max {
+		return int(max)
+	}
+	return n
+}
+
+// LimitDatagram returns n, or the burst when that is smaller. Datagram sizes
+// don't follow the rate, LimitBatch does.
+func (l *Limiter) LimitDatagram(n int) int {
+	if l == nil || uint64(n) <= l.burst {
+		return n
+	}
+	return int(l.burst)
+}
+
+// LimitBatch returns how many datagrams of bytesToSend bytes the next batch can
+// hold without going over the burst or what the current rate sends in rateSlice.
+func (l *Limiter) LimitBatch(batchSize, bytesToSend int) int {
+	if l == nil || batchSize <= 1 || bytesToSend <= 0 {
+		return batchSize
+	}
+	limit := l.maxSend() / uint64(bytesToSend)
+	if limit < 1 {
+		return 1
+	}
+	if limit < uint64(batchSize) {
+		return int(limit)
+	}
+	return batchSize
+}
+
+// StreamLimiter returns the limiter of a stream sending on conn, params are the
+// ones of the stream, see ethr.ClientParams.ForStream. Streams paced by the kernel
+// get nil, when their rate can't be set on conn they fall back to a limiter of
+// their own and the error is returned. shared, when not nil, is the limiter of
+// all streams of the test.
+func StreamLimiter(params ethr.ClientParams, conn interface{}, shared *Limiter) (*Limiter, error) {
+	var err error
+	if params.Pacing == ethr.PaceKernel && params.BwRate > 0 {
+		err = ethr.SetMaxPacingRate(conn, params.BwRate)
+		if err == nil {
+			return nil, nil
+		}
+		params.Pacing = ethr.PaceUser
+	} else if shared != nil {
+		return shared, nil
+	}
+	return NewLimiter(params), err
+}
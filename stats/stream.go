@@ -0,0 +1,125 @@
+package stats
+
+import (
+	"sync"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+)
+
+// streamWindow is how far behind the highest sequence number duplicates can
+// still be detected, older datagrams are counted as out of order only.
+const streamWindow = 64 * 1024
+
+// StreamTracker follows the sequence numbers and send times of a datagram stream
+// to count lost, out of order and duplicate datagrams and to compute the RFC 3550
+// interarrival jitter.
+type StreamTracker struct {
+	sync.Mutex
+	LastAccess time.Time
+
+	received    uint64
+	outOfOrder  uint64
+	duplicates  uint64
+	highest     uint64
+	seen        []uint64 // bitmap of the sequence numbers in (highest-streamWindow, highest]
+	jitter      float64
+	lastTransit int64
+	hasTransit  bool
+}
+
+func NewStreamTracker() *StreamTracker {
+	return &StreamTracker{
+		LastAccess: time.Now(),
+		seen:       make([]uint64, streamWindow/64),
+	}
+}
+
+// Add records a datagram, sequence numbers start at 1. A stream starting over at
+// 1, a new test from the same address, resets the statistics.
+func (s *StreamTracker) Add(sequence uint64, sendTime int64, arrival time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	s.LastAccess = arrival
+
+	if sequence == 1 && s.received > 0 && (s.highest >= streamWindow || s.isSeen(1)) {
+		s.reset()
+	}
+	if sequence > s.highest {
+		s.clear(s.highest+1, sequence)
+		s.highest = sequence
+	} else if s.highest-sequence >= streamWindow {
+		s.received++
+		s.outOfOrder++
+		return
+	} else if s.isSeen(sequence) {
+		s.duplicates++
+		return
+	} else {
+		s.outOfOrder++
+	}
+	s.markSeen(sequence)
+	s.received++
+
+	// clocks of sender and receiver need not be synchronized, only the difference
+	// between transit times is used
+	transit := arrival.UnixNano() - sendTime
+	if s.hasTransit {
+		d := float64(transit - s.lastTransit)
+		if d < 0 {
+			d = -d
+		}
+		s.jitter += (d - s.jitter) / 16
+	}
+	s.lastTransit = transit
+	s.hasTransit = true
+}
+
+// Report returns the statistics of the stream given the number of datagrams sent,
+// the highest sequence number received is used when the sender's count is unknown.
+func (s *StreamTracker) Report(sent uint64) ethr.StreamReport {
+	s.Lock()
+	defer s.Unlock()
+	expected := sent
+	if s.highest > expected {
+		expected = s.highest
+	}
+	lost := uint64(0)
+	if expected > s.received {
+		lost = expected - s.received
+	}
+	return ethr.StreamReport{
+		Sent:       expected,
+		Received:   s.received,
+		Lost:       lost,
+		OutOfOrder: s.outOfOrder,
+		Duplicates: s.duplicates,
+		Jitter:     int64(s.jitter),
+	}
+}
+
+func (s *StreamTracker) reset() {
+	s.received, s.outOfOrder, s.duplicates, s.highest = 0, 0, 0, 0
+	s.clear(0, streamWindow)
+	s.jitter, s.lastTransit, s.hasTransit = 0, 0, false
+}
+
+func (s *StreamTracker) clear(from, to uint64) {
+	if to-from >= streamWindow {
+		for i := range s.seen {
+			s.seen[i] = 0
+		}
+		return
+	}
+	for seq := from; seq <= to; seq++ {
+		s.seen[(seq%streamWindow)/64] &^= 1 << (seq % 64)
+	}
+}
+
+func (s *StreamTracker) isSeen(sequence uint64) bool {
+	return s.seen[(sequence%streamWindow)/64]&(1<<(sequence%64)) != 0
+}
+
+func (s *StreamTracker) markSeen(sequence uint64) {
+	s.seen[(sequence%streamWindow)/64] |= 1 << (sequence % 64)
+}
@@ -96,7 +96,6 @@ func StartTimer() {
 	// test at _almost_ the same time.
 	sleepUntilNextWholeSecond()
 
-	lastStatsTime = time.Now()
 	ticker := time.NewTicker(time.Second)
 	StatsEnabled = true
 	go func() {
@@ -122,8 +121,6 @@ func StopTimer() {
 	StatsEnabled = false
 }
 
-var lastStatsTime = time.Now()
-
 func LatestStats() NetStat {
 	return latestStats
 }
@@ -136,7 +133,6 @@ var latestStats NetStat
 var historicalStats NetStat
 
 func sampleStats() {
-	lastStatsTime = time.Now()
 	historicalStats = latestStats
 	latestStats = GetNetStats()
 }
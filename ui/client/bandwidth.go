@@ -13,7 +13,7 @@ import (
 
 func (u *UI) PrintBandwidth(test *session.Test, result *session.TestResult) {
 	protocol := test.ID.Protocol
-	if protocol != ethr.TCP && protocol != ethr.UDP {
+	if protocol != ethr.TCP && protocol != ethr.UDP && protocol != ethr.HTTP && protocol != ethr.HTTPS {
 		fmt.Printf("Unsupported protocol for bandwidth test: %s\n", protocol.String())
 		return
 	}
@@ -23,12 +23,24 @@ func (u *UI) PrintBandwidth(test *session.Test, result *session.TestResult) {
 	}
 	switch r := result.Body.(type) {
 	case payloads.BandwidthPayload:
+		bidirectional := test.ClientParam.Bidirectional
+		target := targetLabel(test, r.TargetRate)
 		if u.ShowConnectionStats {
 			for _, conn := range r.ConnectionBandwidths {
-				u.printBandwidthResult(protocol, conn.ConnectionID, conn.Bandwidth, conn.PacketsPerSecond)
+				u.printBandwidthResult(protocol, conn.ConnectionID, directionLabel(bidirectional, conn.Direction), conn.Bandwidth, conn.PacketsPerSecond, "")
+				if conn.TCPInfo != nil {
+					u.printTCPInfo(conn.TCPInfo)
+				}
 			}
 		}
-		u.printBandwidthResult(protocol, "SUM", r.TotalBandwidth, r.TotalPacketsPerSecond)
+		if bidirectional {
+			for _, d := range r.DirectionBandwidths {
+				u.printBandwidthResult(protocol, "SUM", d.Direction.String(), d.Bandwidth, d.PacketsPerSecond, target)
+			}
+			// both directions aim at the target
+			target = ""
+		}
+		u.printBandwidthResult(protocol, "SUM", directionLabel(bidirectional, ethr.Upstream, ethr.Downstream), r.TotalBandwidth, r.TotalPacketsPerSecond, target)
 		u.Logger.TestResult(ethr.TestTypeBandwidth, true, protocol, test.RemoteIP, test.RemotePort, r)
 	default:
 		if r != nil {
@@ -37,22 +49,63 @@ func (u *UI) PrintBandwidth(test *session.Test, result *session.TestResult) {
 	}
 }
 
-func (u *UI) PrintBandwidthHeader(p ethr.Protocol) {
+// PrintBandwidthHeader adds a column with the target rate of tests with a traffic
+// profile.
+func (u *UI) PrintBandwidthHeader(p ethr.Protocol, bidirectional, target bool) {
+	dir := ""
+	if bidirectional {
+		dir = "Dir   "
+	}
+	targetColumn := ""
+	if target {
+		targetColumn = fmt.Sprintf(" %10s", "Target")
+	}
 	if p == ethr.UDP {
 		// Printing packets only makes sense for UDP as it is a datagram protocol.
 		// For TCP, TCP itself decides how to chunk the stream to send as packets.
 		fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - - - -")
-		fmt.Printf("%10s %12s %14s %10s %10s\n", "[  ID  ]", "Protocol", "Interval", "Bits/s", "Pkts/s")
+		fmt.Printf("%10s %12s   %s%11s %10s %10s%s\n", "[  ID  ]", "Protocol", dir, "Interval", "Bits/s", "Pkts/s", targetColumn)
 	} else {
 		fmt.Println("- - - - - - - - - - - - - - - - - - - - - - -")
-		fmt.Printf("%10s %12s %14s %10s\n", "[  ID  ]", "Protocol", "Interval", "Bits/s")
+		fmt.Printf("%10s %12s   %s%11s %10s%s\n", "[  ID  ]", "Protocol", dir, "Interval", "Bits/s", targetColumn)
 	}
 }
 
-func (u *UI) printBandwidthResult(p ethr.Protocol, id string, bw, pps uint64) {
+// targetLabel is the target rate of an interval, empty for tests without a
+// traffic profile.
+func targetLabel(test *session.Test, rate uint64) string {
+	if test.ClientParam.Profile.Kind == ethr.ProfileConstant {
+		return ""
+	}
+	return ui.BytesToRate(rate)
+}
+
+// directionLabel is only shown for bidirectional tests, a label for more than one
+// direction is used by the totals of both.
+func directionLabel(bidirectional bool, directions ...ethr.Direction) string {
+	if !bidirectional {
+		return ""
+	}
+	if len(directions) > 1 {
+		return "Both"
+	}
+	return directions[0].String()
+}
+
+func (u *UI) printBandwidthResult(p ethr.Protocol, id, direction string, bw, pps uint64, target string) {
+	if direction != "" {
+		direction = fmt.Sprintf("%-4s  ", direction)
+	}
+	if target != "" {
+		target = fmt.Sprintf("   %7s", target)
+	}
 	if p == ethr.UDP {
-		fmt.Printf("[%5s]     %-5s    %03d-%03d sec   %7s   %7s\n", id, p, u.lastPrintSeconds, u.currentPrintSeconds, ui.BytesToRate(bw), ui.PpsToString(pps))
+		fmt.Printf("[%5s]     %-5s    %s%03d-%03d sec   %7s   %7s%s\n", id, p, direction, u.lastPrintSeconds, u.currentPrintSeconds, ui.BytesToRate(bw), ui.PpsToString(pps), target)
 	} else {
-		fmt.Printf("[%5s]     %-5s    %03d-%03d sec   %7s\n", id, p, u.lastPrintSeconds, u.currentPrintSeconds, ui.BytesToRate(bw))
+		fmt.Printf("[%5s]     %-5s    %s%03d-%03d sec   %7s%s\n", id, p, direction, u.lastPrintSeconds, u.currentPrintSeconds, ui.BytesToRate(bw), target)
 	}
 }
+
+func (u *UI) printTCPInfo(info *payloads.TCPInfo) {
+	fmt.Printf("%10s %s\n", "", info)
+}
@@ -12,7 +12,7 @@ import (
 func (u *UI) PrintConnectionsPerSecond(test *session.Test, result *session.TestResult) {
 	switch r := result.Body.(type) {
 	case payloads.ConnectionsPerSecondPayload:
-		u.printConnectionsResult(test.ID.Protocol, r.Connections)
+		u.printConnectionsResult(test.ID.Protocol, r)
 		u.Logger.TestResult(ethr.TestTypeConnectionsPerSecond, result.Success, test.ID.Protocol, test.RemoteIP, test.RemotePort, r)
 	default:
 		if r != nil {
@@ -22,11 +22,68 @@ func (u *UI) PrintConnectionsPerSecond(test *session.Test, result *session.TestR
 	}
 }
 
-func (u *UI) PrintConnectionsHeader() {
+func (u *UI) PrintConnectionsHeader(p ethr.Protocol) {
 	fmt.Println("- - - - - - - - - - - - - - - - - - ")
-	fmt.Printf("Protocol    Interval      Conn/s\n")
+	if p == ethr.HTTP || p == ethr.HTTPS {
+		// HTTP connections/s tests measure requests, connections are reused with keep-alive
+		fmt.Printf("Protocol    Interval       Req/s    Failed/s\n")
+	} else {
+		fmt.Printf("Protocol    Interval      Conn/s\n")
+	}
 }
 
-func (u *UI) printConnectionsResult(protocol ethr.Protocol, cps uint64) {
-	fmt.Printf("  %-5s    %03d-%03d sec   %7s\n", protocol.String(), u.lastPrintSeconds, u.currentPrintSeconds, ui.CpsToString(cps))
+func (u *UI) printConnectionsResult(protocol ethr.Protocol, r payloads.ConnectionsPerSecondPayload) {
+	if protocol == ethr.HTTP || protocol == ethr.HTTPS {
+		fmt.Printf("  %-5s    %03d-%03d sec   %7s    %8s\n", protocol.String(), u.lastPrintSeconds, u.currentPrintSeconds,
+			ui.CpsToString(r.Connections), ui.CpsToString(r.Failed))
+		return
+	}
+	fmt.Printf("  %-5s    %03d-%03d sec   %7s\n", protocol.String(), u.lastPrintSeconds, u.currentPrintSeconds, ui.CpsToString(r.Connections))
+}
+
+func (u *UI) PrintMaxConnections(test *session.Test, result *session.TestResult) {
+	switch r := result.Body.(type) {
+	case payloads.MaxConnectionsPayload:
+		fmt.Printf("  %03d-%03d sec %10d %8d %8d %8d %9s %9s %9s %9s\n", u.lastPrintSeconds, u.currentPrintSeconds,
+			r.Open, r.Opened, r.Failed, r.Dropped,
+			ui.DurationToString(r.Latency.Avg),
+			ui.DurationToString(r.Latency.P50),
+			ui.DurationToString(r.Latency.P99),
+			ui.DurationToString(r.Latency.Max))
+		u.Logger.TestResult(ethr.TestTypeMaxConnections, result.Success, test.ID.Protocol, test.RemoteIP, test.RemotePort, r)
+	default:
+		if r != nil {
+			u.printUnknownResultType()
+		}
+	}
+}
+
+func (u *UI) PrintMaxConnectionsHeader() {
+	fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -")
+	fmt.Printf("%14s %10s %8s %8s %8s %9s %9s %9s %9s\n", "Interval", "Open", "Opened", "Failed", "Dropped", "Conn Avg", "Conn 50%", "Conn 99%", "Conn Max")
+}
+
+// printMaxConnectionsSummary shows the most connections held at once, the time
+// to connect at each level and why connections started failing.
+func (u *UI) printMaxConnectionsSummary(test *session.Test, summary payloads.MaxConnectionsSummaryPayload) {
+	fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -")
+	fmt.Printf("Peak: %d connections held at once, %d dropped while held\n", summary.Peak, summary.Dropped)
+	if summary.Failed() {
+		fmt.Printf("Failures started at %d connections after %s: %s\n", summary.FirstFailureOpen,
+			ui.DurationToString(summary.FirstFailureAfter), summary.FirstFailure)
+		for f, n := range summary.Failures {
+			if n > 0 {
+				fmt.Printf("%20s: %d failed\n", ethr.ConnectFailure(f), n)
+			}
+		}
+	} else {
+		fmt.Println("No connection failed")
+	}
+	fmt.Printf("%12s %10s %9s %9s %9s %9s\n", "Open", "Opened", "Conn Avg", "Conn 50%", "Conn 99%", "Conn Max")
+	for _, l := range summary.Levels {
+		fmt.Printf("%12s %10d %9s %9s %9s %9s\n", fmt.Sprintf("<=%d", l.UpTo), l.Opened,
+			ui.DurationToString(l.Latency.Avg), ui.DurationToString(l.Latency.P50),
+			ui.DurationToString(l.Latency.P99), ui.DurationToString(l.Latency.Max))
+	}
+	u.Logger.TestResult(test.ID.Type, true, test.ID.Protocol, test.RemoteIP, test.RemotePort, summary)
 }
@@ -12,8 +12,19 @@ import (
 func (u *UI) PrintLatency(test *session.Test, result *session.TestResult) {
 	switch r := result.Body.(type) {
 	case payloads.LatencyPayload:
+		fmt.Printf("%s\n", r)
+		if r.TCPInfo != nil {
+			u.printTCPInfo(r.TCPInfo)
+		}
+		u.Logger.TestResult(ethr.TestTypeLatency, result.Success, test.ID.Protocol, test.RemoteIP, test.RemotePort, r)
+	case payloads.UDPLatencyPayload:
 		fmt.Printf("%s\n", r)
 		u.Logger.TestResult(ethr.TestTypeLatency, result.Success, test.ID.Protocol, test.RemoteIP, test.RemotePort, r)
+	case payloads.TLSPayload:
+		for _, h := range r.Handshakes {
+			fmt.Printf("%s\n", h)
+		}
+		u.Logger.TestResult(ethr.TestTypeLatency, result.Success, test.ID.Protocol, test.RemoteIP, test.RemotePort, r)
 	default:
 		if r != nil {
 			u.printUnknownResultType()
@@ -21,7 +32,18 @@ func (u *UI) PrintLatency(test *session.Test, result *session.TestResult) {
 	}
 }
 
-func (u *UI) PrintLatencyHeader() {
+func (u *UI) PrintLatencyHeader(p ethr.Protocol) {
+	if p == ethr.HTTPS {
+		// HTTPS latency tests measure TLS handshakes per version and cipher suite
+		fmt.Println("-----------------------------------------------------------------------------------------------------------")
+		fmt.Printf("%-44s %9s %9s %9s %9s %9s %9s\n", "Profile", "Full Avg", "Full 50%", "Full 99%", "Res Avg", "Res 50%", "Res 99%")
+		return
+	}
+	if p == ethr.UDP {
+		fmt.Println("-----------------------------------------------------------------------------------------------------------------------")
+		fmt.Printf("%9s %9s %9s %9s %9s %9s %9s %9s %9s %9s %9s %9s\n", "Avg", "Min", "50%", "90%", "95%", "99%", "99.9%", "99.99%", "Max", "Jitter", "Loss", "Late")
+		return
+	}
 	fmt.Println("---------------------------------------------------------------------------------------------------")
 	fmt.Printf("%9s %9s %9s %9s %9s %9s %9s %9s %9s %9s\n", "Avg", "Min", "50%", "90%", "95%", "99%", "99.9%", "99.99%", "Max", "Jitter")
 }
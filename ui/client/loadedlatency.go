@@ -0,0 +1,48 @@
+package client
+
+import (
+	"fmt"
+
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+	"weavelab.xyz/ethr/ui"
+)
+
+func (u *UI) PrintLoadedLatency(test *session.Test, result *session.TestResult) {
+	switch r := result.Body.(type) {
+	case payloads.LoadedLatencySample:
+		u.printLoadedLatencyResult(r.Phase.String(), r.Ping, r.Bandwidth)
+	case payloads.LoadedLatencyPayload:
+		fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -")
+		u.printLoadedLatencyResult("Idle", r.Idle, 0)
+		u.printLoadedLatencyResult("Loaded", r.Loaded, r.Bandwidth)
+		u.printLoadedLatencyResult("Recovery", r.Recovery, 0)
+		fmt.Printf("Latency increase under load: %s, bufferbloat grade: %s\n", ui.DurationToString(r.Increase), r.Grade)
+		u.Logger.TestResult(test.ID.Type, result.Success, test.ID.Protocol, test.RemoteIP, test.RemotePort, r)
+	default:
+		if r != nil {
+			u.printUnknownResultType()
+		}
+	}
+	if result.Error != nil {
+		fmt.Println(result.Error.Error())
+	}
+}
+
+func (u *UI) PrintLoadedLatencyHeader() {
+	fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -")
+	fmt.Printf("%9s %6s %6s %9s %9s %9s %9s %10s\n", "Phase", "Sent", "Lost", "Avg", "Min", "P90", "Max", "Bits/s")
+}
+
+func (u *UI) printLoadedLatencyResult(phase string, p payloads.PingPayload, bw uint64) {
+	rate := "-"
+	if bw > 0 {
+		rate = ui.BytesToRate(bw)
+	}
+	fmt.Printf("%9s %6d %6d %9s %9s %9s %9s %10s\n", phase, p.Sent, p.Lost,
+		ui.DurationToString(p.Latency.Avg),
+		ui.DurationToString(p.Latency.Min),
+		ui.DurationToString(p.Latency.P90),
+		ui.DurationToString(p.Latency.Max),
+		rate)
+}
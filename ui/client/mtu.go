@@ -0,0 +1,32 @@
+package client
+
+import (
+	"fmt"
+	"net"
+
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+)
+
+func (u *UI) PrintMTU(test *session.Test, result *session.TestResult) {
+	switch r := result.Body.(type) {
+	case payloads.MTUProbe:
+		fmt.Println(r)
+	case payloads.MTUPayload:
+		fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -")
+		fmt.Println(r)
+		u.Logger.TestResult(test.ID.Type, result.Success, test.ID.Protocol, test.RemoteIP, test.RemotePort, r)
+	default:
+		if r != nil {
+			u.printUnknownResultType()
+		}
+	}
+	if result.Error != nil {
+		fmt.Println(result.Error.Error())
+	}
+}
+
+func (u *UI) PrintMTUHeader(host net.IP) {
+	fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -")
+	fmt.Printf("Path MTU to %s, probing with the don't fragment bit set\n", host)
+}
@@ -10,21 +10,51 @@ import (
 )
 
 func (u *UI) PrintPacketsPerSecond(test *session.Test, result *session.TestResult) {
+	// nothing was published yet
+	if result.Body == nil {
+		return
+	}
 	switch r := result.Body.(type) {
 	case payloads.BandwidthPayload:
-		u.printPacketsResult(test.ID.Protocol, r)
+		target := targetLabel(test, r.TargetRate)
+		if test.ClientParam.Bidirectional {
+			for _, d := range r.DirectionBandwidths {
+				u.printPacketsResult(test.ID.Protocol, d.Direction.String(), d.Bandwidth, d.PacketsPerSecond, target)
+			}
+			u.printPacketsResult(test.ID.Protocol, "SUM", r.TotalBandwidth, r.TotalPacketsPerSecond, "")
+		} else {
+			u.printPacketsResult(test.ID.Protocol, "", r.TotalBandwidth, r.TotalPacketsPerSecond, target)
+		}
 		u.Logger.TestResult(ethr.TestTypePacketsPerSecond, result.Success, test.ID.Protocol, test.RemoteIP, test.RemotePort, r)
 	default:
 		u.printUnknownResultType()
 	}
 }
 
-func (u *UI) PrintPacketsPerSecondHeader() {
-	fmt.Println("Protocol    Interval      Bits/s    Pkts/s")
+// PrintPacketsPerSecondHeader adds a column with the target rate of tests with a
+// traffic profile.
+func (u *UI) PrintPacketsPerSecondHeader(bidirectional, target bool) {
+	targetColumn := ""
+	if target {
+		targetColumn = "    Target"
+	}
+	if bidirectional {
+		fmt.Println("Protocol  Dir    Interval      Bits/s    Pkts/s" + targetColumn)
+		fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - -")
+		return
+	}
+	fmt.Println("Protocol    Interval      Bits/s    Pkts/s" + targetColumn)
 	fmt.Println("- - - - - - - - - - - - - - - - - - - - - - -")
 
 }
 
-func (u *UI) printPacketsResult(protocol ethr.Protocol, body payloads.BandwidthPayload) {
-	fmt.Printf("  %-5s    %03d-%03d sec   %7s   %7s\n", protocol.String(), u.lastPrintSeconds, u.currentPrintSeconds, ui.BytesToRate(body.TotalBandwidth), ui.PpsToString(body.TotalPacketsPerSecond))
+func (u *UI) printPacketsResult(protocol ethr.Protocol, direction string, bw, pps uint64, target string) {
+	if target != "" {
+		target = fmt.Sprintf("   %7s", target)
+	}
+	if direction != "" {
+		fmt.Printf("  %-5s   %-4s   %03d-%03d sec   %7s   %7s%s\n", protocol.String(), direction, u.lastPrintSeconds, u.currentPrintSeconds, ui.BytesToRate(bw), ui.PpsToString(pps), target)
+		return
+	}
+	fmt.Printf("  %-5s    %03d-%03d sec   %7s   %7s%s\n", protocol.String(), u.lastPrintSeconds, u.currentPrintSeconds, ui.BytesToRate(bw), ui.PpsToString(pps), target)
 }
@@ -13,11 +13,11 @@ import (
 func (u *UI) PrintPing(test *session.Test, result *session.TestResult) {
 	switch r := result.Body.(type) {
 	case payloads.PingPayload:
-		u.PrintPingHeader(test.RemoteIP)
+		u.PrintPingHeader(test.ID.Protocol, test.RemoteIP)
 		u.printPingResult(r.Sent, r.Lost, r.Received)
 		u.Logger.TestResult(ethr.TestTypePing, result.Success, test.ID.Protocol, test.RemoteIP, test.RemotePort, r)
 		if r.Received > 0 {
-			u.PrintLatencyHeader()
+			u.PrintLatencyHeader(test.ID.Protocol)
 			fmt.Printf("%s\n", r.Latency)
 		}
 	default:
@@ -27,8 +27,12 @@ func (u *UI) PrintPing(test *session.Test, result *session.TestResult) {
 	}
 }
 
-func (u *UI) PrintPingHeader(host net.IP) {
+func (u *UI) PrintPingHeader(p ethr.Protocol, host net.IP) {
 	fmt.Println("-----------------------------------------------------------------------------------------")
+	if p == ethr.ICMP {
+		fmt.Printf("ICMP ping statistics for %s:\n", host.String())
+		return
+	}
 	fmt.Printf("TCP connect statistics for %s:\n", host.String())
 }
 
@@ -0,0 +1,206 @@
+package client
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+	"weavelab.xyz/ethr/ui"
+)
+
+// PrintSummary prints the results that are only known once the test is done.
+func (u *UI) PrintSummary(test *session.Test) {
+	streams := make([]payloads.DatagramStatsPayload, 0)
+	options := make([]payloads.StreamOptionsPayload, 0)
+	var cost *payloads.CPUCostPayload
+	var sizes *payloads.SizeBucketsPayload
+	var maxConnections *payloads.MaxConnectionsSummaryPayload
+	integrity := make([]payloads.IntegrityPayload, 0)
+	for _, r := range test.Summaries() {
+		switch body := r.Body.(type) {
+		case payloads.DatagramStatsPayload:
+			streams = append(streams, body)
+		case payloads.StreamOptionsPayload:
+			options = append(options, body)
+		case payloads.CPUCostPayload:
+			cost = &body
+		case payloads.SizeBucketsPayload:
+			sizes = &body
+		case payloads.MaxConnectionsSummaryPayload:
+			maxConnections = &body
+		case payloads.IntegrityPayload:
+			integrity = append(integrity, body)
+		default:
+			if body != nil {
+				u.printUnknownResultType()
+			}
+		}
+	}
+	if len(streams) > 0 {
+		u.printDatagramStats(test, streams)
+	}
+	if len(options) > 0 {
+		u.printStreamOptions(test, options)
+	}
+	if cost != nil {
+		u.printCPUCost(test, *cost)
+	}
+	if sizes != nil {
+		u.printSizeBuckets(test, *sizes)
+	}
+	if maxConnections != nil {
+		u.printMaxConnectionsSummary(test, *maxConnections)
+	}
+	if len(integrity) > 0 {
+		u.printIntegrity(test, integrity)
+	}
+}
+
+// printIntegrity shows what was verified of the payload of each stream, by the
+// client of those it received and by the server of those it did.
+func (u *UI) printIntegrity(test *session.Test, streams []payloads.IntegrityPayload) {
+	sort.SliceStable(streams, func(i, j int) bool {
+		if streams[i].ConnectionID == streams[j].ConnectionID {
+			return streams[i].Direction < streams[j].Direction
+		}
+		return connectionIDLess(streams[i].ConnectionID, streams[j].ConnectionID)
+	})
+
+	corrupted := uint64(0)
+	fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -")
+	fmt.Printf("%10s %5s %10s %12s %14s\n", "[  ID  ]", "Dir", "Verified", "Corrupted", "First offset")
+	for _, s := range streams {
+		first := "-"
+		if s.Corrupted > 0 {
+			first = fmt.Sprintf("%d", s.FirstCorruption)
+		}
+		fmt.Printf("[%5s]   %5s %10s %12d %14s\n", s.ConnectionID, s.Direction, ui.NumberToUnit(s.Verified), s.Corrupted, first)
+		corrupted += s.Corrupted
+		u.Logger.TestResult(test.ID.Type, true, test.ID.Protocol, test.RemoteIP, test.RemotePort, s)
+	}
+	if corrupted > 0 {
+		fmt.Printf("Payload corrupted: %d bytes did not match what was sent\n", corrupted)
+	} else {
+		fmt.Println("Payload intact: every byte received matched what was sent")
+	}
+}
+
+// printSizeBuckets shows what the client sent by the size of its messages, with
+// the round trip latencies of each size for latency tests.
+func (u *UI) printSizeBuckets(test *session.Test, sizes payloads.SizeBucketsPayload) {
+	total := uint64(0)
+	for _, b := range sizes.Buckets {
+		total += b.Bytes
+	}
+	fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -")
+	if test.ID.Type == ethr.TestTypeLatency {
+		fmt.Printf("%10s %10s %9s %9s %9s %9s %9s\n", "Size", "Messages", "Avg", "Min", "50%", "99%", "Max")
+	} else {
+		fmt.Printf("%10s %10s %10s %8s %12s\n", "Size", "Messages", "Bytes", "Share", "Write rate")
+	}
+	for _, b := range sizes.Buckets {
+		size := fmt.Sprintf("<=%d", b.UpTo)
+		if b.Latency != nil {
+			fmt.Printf("%10s %10d %9s %9s %9s %9s %9s\n", size, b.Messages,
+				ui.DurationToString(b.Latency.Avg), ui.DurationToString(b.Latency.Min), ui.DurationToString(b.Latency.P50),
+				ui.DurationToString(b.Latency.P99), ui.DurationToString(b.Latency.Max))
+		} else {
+			share := 0.0
+			if total > 0 {
+				share = 100 * float64(b.Bytes) / float64(total)
+			}
+			fmt.Printf("%10s %10d %10s %7.2f%% %12s\n", size, b.Messages, ui.NumberToUnit(b.Bytes), share, ui.BytesToRate(b.WriteRate()))
+		}
+		u.Logger.TestResult(test.ID.Type, true, test.ID.Protocol, test.RemoteIP, test.RemotePort, b)
+	}
+}
+
+// printCPUCost shows the CPU time the client spent per gigabit it sent or
+// received, for comparing the send modes.
+func (u *UI) printCPUCost(test *session.Test, cost payloads.CPUCostPayload) {
+	fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -")
+	fmt.Printf("Send mode: %s, sent: %sB, received: %sB\n", cost.Mode, ui.NumberToUnit(cost.Sent), ui.NumberToUnit(cost.Received))
+	fmt.Printf("CPU time: %s user, %s system, %s per gigabit\n",
+		ui.DurationToString(cost.User), ui.DurationToString(cost.System), ui.DurationToString(cost.PerGigabit()))
+	if cost.Mode == ethr.SendMsgZeroCopy {
+		fmt.Printf("MSG_ZEROCOPY sends completed: %d without copy, %d copied by the kernel\n", cost.ZeroCopied, cost.Copied)
+	}
+	u.Logger.TestResult(test.ID.Type, true, test.ID.Protocol, test.RemoteIP, test.RemotePort, cost)
+}
+
+// printStreamOptions shows the socket options the kernel granted the client end
+// of each stream and the congestion control of the server end, which is the one
+// sending in reverse mode. The server logs the other options of its end.
+func (u *UI) printStreamOptions(test *session.Test, options []payloads.StreamOptionsPayload) {
+	sort.SliceStable(options, func(i, j int) bool {
+		return connectionIDLess(options[i].ConnectionID, options[j].ConnectionID)
+	})
+
+	fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -")
+	fmt.Printf("%10s %8s %8s %8s %10s %10s %7s %8s\n", "[  ID  ]", "CC", "SrvCC", "NoDelay", "SndBuf", "RcvBuf", "MSS", "Linger")
+	for _, o := range options {
+		fmt.Printf("[%5s]   %8s %8s %8t %10s %10s %7d %8s\n", o.ConnectionID, o.Options.CCString(), o.Server.CCString(), o.Options.NoDelay,
+			ui.NumberToUnit(uint64(o.Options.SendBuffer)), ui.NumberToUnit(uint64(o.Options.ReceiveBuffer)), o.Options.MSS, o.Options.LingerString())
+		u.Logger.TestResult(test.ID.Type, true, test.ID.Protocol, test.RemoteIP, test.RemotePort, o)
+	}
+}
+
+func (u *UI) printDatagramStats(test *session.Test, streams []payloads.DatagramStatsPayload) {
+	sort.SliceStable(streams, func(i, j int) bool {
+		if streams[i].ConnectionID == streams[j].ConnectionID {
+			return streams[i].Direction < streams[j].Direction
+		}
+		return connectionIDLess(streams[i].ConnectionID, streams[j].ConnectionID)
+	})
+
+	fmt.Println("- - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - - -")
+	fmt.Printf("%10s %5s %10s %10s %10s %8s %8s %8s %9s\n", "[  ID  ]", "Dir", "Sent", "Received", "Lost", "Loss", "OOO", "Dup", "Jitter")
+	// the receiver reports each direction, sum them separately
+	sums := make(map[ethr.Direction]*payloads.DatagramStatsPayload)
+	jitters := make(map[ethr.Direction][]time.Duration)
+	for _, s := range streams {
+		if u.ShowConnectionStats {
+			u.printDatagramStatsResult(s)
+		}
+		u.Logger.TestResult(ethr.TestTypeBandwidth, true, test.ID.Protocol, test.RemoteIP, test.RemotePort, s)
+		sum, ok := sums[s.Direction]
+		if !ok {
+			sum = &payloads.DatagramStatsPayload{ConnectionID: "SUM", Direction: s.Direction}
+			sums[s.Direction] = sum
+		}
+		sum.Sent += s.Sent
+		sum.Received += s.Received
+		sum.Lost += s.Lost
+		sum.OutOfOrder += s.OutOfOrder
+		sum.Duplicates += s.Duplicates
+		jitters[s.Direction] = append(jitters[s.Direction], s.Jitter)
+	}
+	for _, d := range []ethr.Direction{ethr.Upstream, ethr.Downstream} {
+		if sum, ok := sums[d]; ok {
+			total := time.Duration(0)
+			for _, j := range jitters[d] {
+				total += j
+			}
+			sum.Jitter = total / time.Duration(len(jitters[d]))
+			u.printDatagramStatsResult(*sum)
+		}
+	}
+}
+
+// connectionIDLess orders connection IDs by number, "10" comes after "2".
+func connectionIDLess(a, b string) bool {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return x < y
+}
+
+func (u *UI) printDatagramStatsResult(s payloads.DatagramStatsPayload) {
+	fmt.Printf("[%5s]    %5s %10d %10d %10d %7.2f%% %8d %8d %9s\n", s.ConnectionID, s.Direction, s.Sent, s.Received, s.Lost, s.LossPercent(), s.OutOfOrder, s.Duplicates, ui.DurationToString(s.Jitter))
+}
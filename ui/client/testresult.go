@@ -17,7 +17,8 @@ func (u *UI) PrintTestResults(ctx context.Context, test *session.Test) {
 	var previousResult, latestResult *session.TestResult
 
 	tickInterval := 250 * time.Millisecond
-	if test.ID.Type == ethr.TestTypeBandwidth || test.ID.Type == ethr.TestTypePacketsPerSecond || test.ID.Type == ethr.TestTypeConnectionsPerSecond {
+	if test.ID.Type == ethr.TestTypeBandwidth || test.ID.Type == ethr.TestTypePacketsPerSecond || test.ID.Type == ethr.TestTypeConnectionsPerSecond ||
+		test.ID.Type == ethr.TestTypeRequestResponse || test.ID.Type == ethr.TestTypeConnectRequestResponse || test.ID.Type == ethr.TestTypeMaxConnections {
 		tickInterval = time.Second
 	}
 	paintTicker := time.NewTicker(tickInterval)
@@ -32,7 +33,7 @@ func (u *UI) PrintTestResults(ctx context.Context, test *session.Test) {
 			}
 		case ethr.TestTypePacketsPerSecond:
 			if !displayedHeader {
-				u.PrintPacketsPerSecondHeader()
+				u.PrintPacketsPerSecondHeader(test.ClientParam.Bidirectional, test.ClientParam.Profile.Kind != ethr.ProfileConstant)
 				displayedHeader = true
 			}
 			if latestResult != previousResult && latestResult != nil {
@@ -40,7 +41,7 @@ func (u *UI) PrintTestResults(ctx context.Context, test *session.Test) {
 			}
 		case ethr.TestTypeBandwidth:
 			if !displayedHeader {
-				u.PrintBandwidthHeader(test.ID.Protocol)
+				u.PrintBandwidthHeader(test.ID.Protocol, test.ClientParam.Bidirectional, test.ClientParam.Profile.Kind != ethr.ProfileConstant)
 				displayedHeader = true
 			}
 			if latestResult != previousResult && latestResult != nil {
@@ -48,7 +49,7 @@ func (u *UI) PrintTestResults(ctx context.Context, test *session.Test) {
 			}
 		case ethr.TestTypeLatency:
 			if !displayedHeader {
-				u.PrintLatencyHeader()
+				u.PrintLatencyHeader(test.ID.Protocol)
 				displayedHeader = true
 			}
 			if latestResult != previousResult && latestResult != nil {
@@ -56,12 +57,28 @@ func (u *UI) PrintTestResults(ctx context.Context, test *session.Test) {
 			}
 		case ethr.TestTypeConnectionsPerSecond:
 			if !displayedHeader {
-				u.PrintConnectionsHeader()
+				u.PrintConnectionsHeader(test.ID.Protocol)
 				displayedHeader = true
 			}
 			if latestResult != previousResult && latestResult != nil {
 				u.PrintConnectionsPerSecond(test, latestResult)
 			}
+		case ethr.TestTypeRequestResponse, ethr.TestTypeConnectRequestResponse:
+			if !displayedHeader {
+				u.PrintTransactionsHeader(test.ID.Type)
+				displayedHeader = true
+			}
+			if latestResult != previousResult && latestResult != nil {
+				u.PrintTransactions(test, latestResult)
+			}
+		case ethr.TestTypeMaxConnections:
+			if !displayedHeader {
+				u.PrintMaxConnectionsHeader()
+				displayedHeader = true
+			}
+			if latestResult != previousResult && latestResult != nil {
+				u.PrintMaxConnections(test, latestResult)
+			}
 		case ethr.TestTypeTraceRoute:
 			fallthrough
 		case ethr.TestTypeMyTraceRoute:
@@ -82,6 +99,40 @@ func (u *UI) PrintTestResults(ctx context.Context, test *session.Test) {
 				u.PrintTraceroute(test, &r)
 			default:
 			}
+		case ethr.TestTypeMTU:
+			if !displayedHeader {
+				u.PrintMTUHeader(test.RemoteIP)
+				displayedHeader = true
+			}
+			if exiting {
+				for r := range test.Results {
+					u.PrintMTU(test, &r)
+				}
+				return
+			}
+
+			select {
+			case r := <-test.Results:
+				u.PrintMTU(test, &r)
+			default:
+			}
+		case ethr.TestTypeLatencyUnderLoad:
+			if !displayedHeader {
+				u.PrintLoadedLatencyHeader()
+				displayedHeader = true
+			}
+			if exiting {
+				for r := range test.Results {
+					u.PrintLoadedLatency(test, &r)
+				}
+				return
+			}
+
+			select {
+			case r := <-test.Results:
+				u.PrintLoadedLatency(test, &r)
+			default:
+			}
 		default:
 			u.printUnknownResultType()
 		}
@@ -18,6 +18,9 @@ func (u *UI) PrintTraceroute(test *session.Test, result *session.TestResult) {
 		if test.ID.Type == ethr.TestTypeMyTraceRoute && result.Success {
 			u.PrintTracerouteHeader(test.RemoteIP)
 			fmt.Println(r)
+		} else if len(r.Multipath) > 0 && result.Success {
+			// traceroute printed the hops of the first flow as they were found
+			fmt.Println(payloads.TraceRoutePayload{Multipath: r.Multipath})
 		}
 		u.Logger.TestResult(test.ID.Type, result.Success, test.ID.Protocol, test.RemoteIP, test.RemotePort, r)
 	default:
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"fmt"
+
+	"weavelab.xyz/ethr/ethr"
+	"weavelab.xyz/ethr/session"
+	"weavelab.xyz/ethr/session/payloads"
+	"weavelab.xyz/ethr/ui"
+)
+
+func (u *UI) PrintTransactions(test *session.Test, result *session.TestResult) {
+	switch r := result.Body.(type) {
+	case payloads.TransactionsPayload:
+		fmt.Printf("  %03d-%03d sec %9s", u.lastPrintSeconds, u.currentPrintSeconds, ui.CpsToString(r.TransactionsPerSecond))
+		if test.ID.Type == ethr.TestTypeConnectRequestResponse {
+			fmt.Printf(" %9s", ui.CpsToString(r.FailedPerSecond))
+		}
+		fmt.Printf(" %9s %9s %9s %9s %9s %9s %9s\n",
+			ui.DurationToString(r.Latency.Avg),
+			ui.DurationToString(r.Latency.Min),
+			ui.DurationToString(r.Latency.P50),
+			ui.DurationToString(r.Latency.P90),
+			ui.DurationToString(r.Latency.P99),
+			ui.DurationToString(r.Latency.P999),
+			ui.DurationToString(r.Latency.Max))
+		u.Logger.TestResult(test.ID.Type, result.Success, test.ID.Protocol, test.RemoteIP, test.RemotePort, r)
+	default:
+		if r != nil {
+			u.printUnknownResultType()
+		}
+	}
+}
+
+func (u *UI) PrintTransactionsHeader(testType ethr.TestType) {
+	fmt.Println("-----------------------------------------------------------------------------------------------")
+	fmt.Printf("%14s %9s", "Interval", "Trans/s")
+	if testType == ethr.TestTypeConnectRequestResponse {
+		// opening the connection of a TCP_CRR transaction can fail
+		fmt.Printf(" %9s", "Failed/s")
+	}
+	fmt.Printf(" %9s %9s %9s %9s %9s %9s %9s\n", "Avg", "Min", "50%", "90%", "99%", "99.9%", "Max")
+}
@@ -10,16 +10,20 @@ import (
 )
 
 type RawUI struct {
-	tcpStats  *AggregateStats
-	udpStats  *AggregateStats
-	icmpStats *AggregateStats
+	tcpStats   *AggregateStats
+	udpStats   *AggregateStats
+	icmpStats  *AggregateStats
+	httpStats  *AggregateStats
+	httpsStats *AggregateStats
 }
 
-func InitRawUI(tcp *AggregateStats, udp *AggregateStats, icmp *AggregateStats) (*RawUI, error) {
+func InitRawUI(tcp *AggregateStats, udp *AggregateStats, icmp *AggregateStats, http *AggregateStats, https *AggregateStats) (*RawUI, error) {
 	return &RawUI{
-		tcpStats:  tcp,
-		udpStats:  udp,
-		icmpStats: icmp,
+		tcpStats:   tcp,
+		udpStats:   udp,
+		icmpStats:  icmp,
+		httpStats:  http,
+		httpsStats: https,
 	}, nil
 }
 
@@ -40,6 +44,12 @@ func (u *RawUI) Paint(seconds uint64) {
 
 		icmpResults := u.getTestResults(&s, ethr.ICMP, u.icmpStats)
 		u.printTestResults(icmpResults)
+
+		httpResults := u.getTestResults(&s, ethr.HTTP, u.httpStats)
+		u.printTestResults(httpResults)
+
+		httpsResults := u.getTestResults(&s, ethr.HTTPS, u.httpsStats)
+		u.printTestResults(httpsResults)
 	}
 
 	tcpAgg := u.tcpStats.ToString(ethr.TCP)
@@ -53,6 +63,14 @@ func (u *RawUI) Paint(seconds uint64) {
 	icmpAgg := u.icmpStats.ToString(ethr.ICMP)
 	u.icmpStats.Reset()
 	u.printTestResults(icmpAgg)
+
+	httpAgg := u.httpStats.ToString(ethr.HTTP)
+	u.httpStats.Reset()
+	u.printTestResults(httpAgg)
+
+	httpsAgg := u.httpsStats.ToString(ethr.HTTPS)
+	u.httpsStats.Reset()
+	u.printTestResults(httpsAgg)
 }
 
 func (u *RawUI) printTestHeader() {
@@ -62,6 +80,9 @@ func (u *RawUI) printTestHeader() {
 }
 
 func (u *RawUI) printTestResults(results []string) {
+	if len(results) == 0 {
+		return
+	}
 	fmt.Printf("[%13s]  %5s  %7s  %7s  %7s  %8s\n", ui.TruncateStringFromStart(results[0], 13), results[1], results[2], results[3], results[4], results[5])
 }
 
@@ -77,6 +98,13 @@ func (u *RawUI) getTestResults(s *session.Session, protocol ethr.Protocol, agg *
 			bw = body.Bandwidth
 			agg.Bandwidth += body.Bandwidth
 
+			if protocol == ethr.HTTP || protocol == ethr.HTTPS {
+				// requests/s are reported as connections/s for HTTP
+				cpsTestOn = true
+				cps = body.ConnectionsPerSecond
+				agg.ConnectionsPerSecond += body.ConnectionsPerSecond
+			}
+
 			if protocol == ethr.TCP {
 				cpsTestOn = true
 				cps = body.ConnectionsPerSecond
@@ -90,7 +118,7 @@ func (u *RawUI) getTestResults(s *session.Session, protocol ethr.Protocol, agg *
 				}
 			}
 
-			if protocol == ethr.UDP {
+			if protocol == ethr.UDP || protocol == ethr.ICMP {
 				ppsTestOn = true
 				pps = body.PacketsPerSecond
 				agg.PacketsPerSecond += body.PacketsPerSecond
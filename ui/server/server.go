@@ -17,18 +17,20 @@ type UI struct {
 	Terminal ServerUI
 	isTui    bool
 
-	TCP  *AggregateStats
-	ICMP *AggregateStats
-	UDP  *AggregateStats
+	TCP   *AggregateStats
+	ICMP  *AggregateStats
+	UDP   *AggregateStats
+	HTTP  *AggregateStats
+	HTTPS *AggregateStats
 }
 
 func NewUI(terminalUI bool) *UI {
 	var ui ServerUI
 	var err error
 
-	tcp, udp, icmp := NewAggregateStats(), NewAggregateStats(), NewAggregateStats()
+	tcp, udp, icmp, http, https := NewAggregateStats(), NewAggregateStats(), NewAggregateStats(), NewAggregateStats(), NewAggregateStats()
 	if terminalUI {
-		ui, err = InitTui(tcp, udp, icmp)
+		ui, err = InitTui(tcp, udp, icmp, http, https)
 		if err != nil {
 			fmt.Println("Error: Failed to initialize UI.", err)
 			fmt.Println("Using command line view instead of UI")
@@ -37,16 +39,18 @@ func NewUI(terminalUI bool) *UI {
 
 	if ui == nil {
 		terminalUI = false
-		ui, _ = InitRawUI(tcp, udp, icmp)
+		ui, _ = InitRawUI(tcp, udp, icmp, http, https)
 	}
 
 	return &UI{
 		Terminal: ui,
 		isTui:    terminalUI,
 
-		TCP:  tcp,
-		UDP:  udp,
-		ICMP: icmp,
+		TCP:   tcp,
+		UDP:   udp,
+		ICMP:  icmp,
+		HTTP:  http,
+		HTTPS: https,
 	}
 }
 
@@ -16,9 +16,11 @@ import (
 )
 
 type Tui struct {
-	tcpStats  *AggregateStats
-	udpStats  *AggregateStats
-	icmpStats *AggregateStats
+	tcpStats   *AggregateStats
+	udpStats   *AggregateStats
+	icmpStats  *AggregateStats
+	httpStats  *AggregateStats
+	httpsStats *AggregateStats
 
 	h, w                               int
 	resX, resY, resW                   int
@@ -38,7 +40,7 @@ type Tui struct {
 	ringLock                           sync.RWMutex
 }
 
-func InitTui(tcp *AggregateStats, udp *AggregateStats, icmp *AggregateStats) (*Tui, error) {
+func InitTui(tcp *AggregateStats, udp *AggregateStats, icmp *AggregateStats, http *AggregateStats, https *AggregateStats) (*Tui, error) {
 	err := tm.Init()
 	if err != nil {
 		return nil, err
@@ -86,9 +88,11 @@ func InitTui(tcp *AggregateStats, udp *AggregateStats, icmp *AggregateStats) (*T
 		errX:       msgW + 1,
 		errY:       h - botScnH + 1,
 
-		tcpStats:  tcp,
-		udpStats:  udp,
-		icmpStats: icmp,
+		tcpStats:   tcp,
+		udpStats:   udp,
+		icmpStats:  icmp,
+		httpStats:  http,
+		httpsStats: https,
 	}
 
 	tui.resultHdr = []string{"RemoteAddress", "Proto", "Bits/s", "Conn/s", "Pkts/s", "Avg Latency"}
@@ -178,7 +182,7 @@ func (t *Tui) Paint(nanos uint64) {
 		t.res.addTblRow(t.resultHdr)
 		t.res.addTblSpr()
 	}
-	tcpActive, udpActive, icmpActive := false, false, false
+	tcpActive, udpActive, icmpActive, httpActive, httpsActive := false, false, false, false, false
 	for _, s := range sessions {
 		tcpResults := t.getTestResults(&s, ethr.TCP, t.tcpStats)
 		if len(tcpResults) > 0 {
@@ -200,6 +204,20 @@ func (t *Tui) Paint(nanos uint64) {
 			t.res.addTblSpr()
 			icmpActive = true
 		}
+
+		httpResults := t.getTestResults(&s, ethr.HTTP, t.httpStats)
+		if len(httpResults) > 0 {
+			t.res.addTblRow(httpResults)
+			t.res.addTblSpr()
+			httpActive = true
+		}
+
+		httpsResults := t.getTestResults(&s, ethr.HTTPS, t.httpsStats)
+		if len(httpsResults) > 0 {
+			t.res.addTblRow(httpsResults)
+			t.res.addTblSpr()
+			httpsActive = true
+		}
 	}
 
 	if len(sessions) > 0 {
@@ -223,6 +241,20 @@ func (t *Tui) Paint(nanos uint64) {
 			t.res.addTblRow(icmpAgg)
 			t.res.addTblSpr()
 		}
+
+		if httpActive {
+			httpAgg := t.httpStats.ToString(ethr.HTTP)
+			t.httpStats.Reset()
+			t.res.addTblRow(httpAgg)
+			t.res.addTblSpr()
+		}
+
+		if httpsActive {
+			httpsAgg := t.httpsStats.ToString(ethr.HTTPS)
+			t.httpsStats.Reset()
+			t.res.addTblRow(httpsAgg)
+			t.res.addTblSpr()
+		}
 	}
 
 	previousStats := stats.PreviousStats()
@@ -276,6 +308,13 @@ func (t *Tui) getTestResults(s *session.Session, protocol ethr.Protocol, agg *Ag
 			bw = body.Bandwidth
 			agg.Bandwidth += body.Bandwidth
 
+			if protocol == ethr.HTTP || protocol == ethr.HTTPS {
+				// requests/s are reported as connections/s for HTTP
+				cpsTestOn = true
+				cps = body.ConnectionsPerSecond
+				agg.ConnectionsPerSecond += body.ConnectionsPerSecond
+			}
+
 			if protocol == ethr.TCP {
 				cpsTestOn = true
 				cps = body.ConnectionsPerSecond
@@ -287,7 +326,7 @@ func (t *Tui) getTestResults(s *session.Session, protocol ethr.Protocol, agg *Ag
 				}
 			}
 
-			if protocol == ethr.UDP {
+			if protocol == ethr.UDP || protocol == ethr.ICMP {
 				ppsTestOn = true
 				pps = body.PacketsPerSecond
 				agg.PacketsPerSecond += body.PacketsPerSecond